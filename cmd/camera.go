@@ -0,0 +1,175 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"go-falling-sand/game"
+)
+
+const (
+	MinZoom = 1
+	MaxZoom = 64
+
+	// ZoomStep is how much one notch of the wheel zooms in or out.
+	ZoomStep = 1.25
+
+	// PanSpeed is how many pixels the arrow keys move the camera per frame.
+	PanSpeed = 8
+)
+
+// Camera maps between world cells and the part of the screen the board is
+// drawn on.
+type Camera struct {
+	// X, Y is the world position shown at the top left of the board.
+	X, Y float32
+	// Zoom is the size of a cell in pixels.
+	Zoom float32
+
+	// View is the part of the screen the board is drawn on.
+	ViewX, ViewY, ViewWidth, ViewHeight float32
+
+	// Unbounded cameras can move anywhere, for infinite worlds.
+	Unbounded bool
+
+	dragging       bool
+	dragX, dragY   int
+	worldW, worldH float32
+}
+
+func NewCamera(zoom, viewX, viewY, viewWidth, viewHeight float32) *Camera {
+	return &Camera{
+		Zoom:       zoom,
+		ViewX:      viewX,
+		ViewY:      viewY,
+		ViewWidth:  viewWidth,
+		ViewHeight: viewHeight,
+	}
+}
+
+// WorldToScreen returns where on the screen a world position is drawn.
+func (camera *Camera) WorldToScreen(x, y float32) (float32, float32) {
+	return (x-camera.X)*camera.Zoom + camera.ViewX, (y-camera.Y)*camera.Zoom + camera.ViewY
+}
+
+// ScreenToWorld returns the world position drawn at a point on the screen.
+func (camera *Camera) ScreenToWorld(x, y float32) (float32, float32) {
+	return (x-camera.ViewX)/camera.Zoom + camera.X, (y-camera.ViewY)/camera.Zoom + camera.Y
+}
+
+// ScreenToCell returns the cell drawn at a point on the screen.
+func (camera *Camera) ScreenToCell(x, y float32) (int, int) {
+	wx, wy := camera.ScreenToWorld(x, y)
+	return int(math.Floor(float64(wx))), int(math.Floor(float64(wy)))
+}
+
+// Contains reports whether a point on the screen is on the board.
+func (camera *Camera) Contains(x, y float32) bool {
+	return x >= camera.ViewX && y >= camera.ViewY && x < camera.ViewX+camera.ViewWidth && y < camera.ViewY+camera.ViewHeight
+}
+
+// View returns the part of screen the board is drawn on. Drawing onto it
+// clips everything to the board.
+func (camera *Camera) View(screen *ebiten.Image) *ebiten.Image {
+	rect := image.Rect(
+		int(camera.ViewX),
+		int(camera.ViewY),
+		int(camera.ViewX+camera.ViewWidth),
+		int(camera.ViewY+camera.ViewHeight),
+	)
+	return screen.SubImage(rect).(*ebiten.Image)
+}
+
+// GeoM transforms an image with one pixel per cell onto the screen.
+func (camera *Camera) GeoM() ebiten.GeoM {
+	geoM := ebiten.GeoM{}
+	geoM.Translate(float64(-camera.X), float64(-camera.Y))
+	geoM.Scale(float64(camera.Zoom), float64(camera.Zoom))
+	geoM.Translate(float64(camera.ViewX), float64(camera.ViewY))
+	return geoM
+}
+
+// VisibleCells returns the rect of world cells that are at least partly on
+// the board.
+func (camera *Camera) VisibleCells() game.Rect {
+	minX, minY := camera.ScreenToCell(camera.ViewX, camera.ViewY)
+	maxX, maxY := camera.ScreenToCell(camera.ViewX+camera.ViewWidth, camera.ViewY+camera.ViewHeight)
+	return game.Rect{MinX: minX, MinY: minY, MaxX: maxX, MaxY: maxY}
+}
+
+// Pan moves the camera by a distance in screen pixels.
+func (camera *Camera) Pan(dx, dy float32) {
+	camera.X += dx / camera.Zoom
+	camera.Y += dy / camera.Zoom
+	camera.Clamp()
+}
+
+// ZoomAt zooms by factor while keeping the world position under the screen
+// point x, y in place.
+func (camera *Camera) ZoomAt(x, y, factor float32) {
+	wx, wy := camera.ScreenToWorld(x, y)
+	camera.Zoom = max(MinZoom, min(camera.Zoom*factor, MaxZoom))
+	camera.X = wx - (x-camera.ViewX)/camera.Zoom
+	camera.Y = wy - (y-camera.ViewY)/camera.Zoom
+	camera.Clamp()
+}
+
+// SetWorldSize sets the size of the world in cells, which the camera keeps
+// at least half a view of on screen.
+func (camera *Camera) SetWorldSize(width, height int) {
+	camera.worldW, camera.worldH = float32(width), float32(height)
+	camera.Clamp()
+}
+
+func (camera *Camera) Clamp() {
+	if camera.Unbounded {
+		return
+	}
+	halfW := camera.ViewWidth / camera.Zoom / 2
+	halfH := camera.ViewHeight / camera.Zoom / 2
+	camera.X = max(-halfW, min(camera.X, camera.worldW-halfW))
+	camera.Y = max(-halfH, min(camera.Y, camera.worldH-halfH))
+}
+
+// Update pans with the arrow keys or by dragging with the middle mouse
+// button, and zooms with ctrl+wheel.
+func (camera *Camera) Update() {
+	var dx, dy float32
+	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
+		dx -= PanSpeed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
+		dx += PanSpeed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
+		dy -= PanSpeed
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
+		dy += PanSpeed
+	}
+	if dx != 0 || dy != 0 {
+		camera.Pan(dx, dy)
+	}
+
+	mx, my := ebiten.CursorPosition()
+	switch {
+	case inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonMiddle) && camera.Contains(float32(mx), float32(my)):
+		camera.dragging = true
+	case camera.dragging && ebiten.IsMouseButtonPressed(ebiten.MouseButtonMiddle):
+		camera.Pan(float32(camera.dragX-mx), float32(camera.dragY-my))
+	default:
+		camera.dragging = false
+	}
+	camera.dragX, camera.dragY = mx, my
+
+	if _, wheel := ebiten.Wheel(); wheel != 0 && ebiten.IsKeyPressed(ebiten.KeyControl) && camera.Contains(float32(mx), float32(my)) {
+		factor := float32(ZoomStep)
+		if wheel < 0 {
+			factor = 1 / factor
+		}
+		camera.ZoomAt(float32(mx), float32(my), factor)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCameraScreenToCell(t *testing.T) {
+	camera := NewCamera(4, 200, 0, 400, 300)
+	camera.SetWorldSize(100, 100)
+	camera.Pan(40, 80)
+
+	tests := []struct {
+		x, y         float32
+		cellX, cellY int
+	}{
+		{200, 0, 10, 20},
+		{203.9, 3.9, 10, 20},
+		{204, 4, 11, 21},
+		{199, 0, 9, 20},
+	}
+	for _, test := range tests {
+		if x, y := camera.ScreenToCell(test.x, test.y); x != test.cellX || y != test.cellY {
+			t.Errorf("screen %v %v shows cell %v %v, expected %v %v", test.x, test.y, x, y, test.cellX, test.cellY)
+		}
+	}
+
+	if x, y := camera.WorldToScreen(camera.ScreenToWorld(321, 123)); x != 321 || y != 123 {
+		t.Errorf("screen 321 123 went to world and back to %v %v", x, y)
+	}
+}
+
+func TestCameraZoomAt(t *testing.T) {
+	camera := NewCamera(4, 200, 0, 400, 300)
+	camera.SetWorldSize(100, 100)
+
+	before, _ := camera.ScreenToWorld(300, 100)
+	camera.ZoomAt(300, 100, 2)
+	if camera.Zoom != 8 {
+		t.Fatalf("zoomed to %v, expected 8", camera.Zoom)
+	}
+	if after, _ := camera.ScreenToWorld(300, 100); math.Abs(float64(after-before)) > 1e-4 {
+		t.Errorf("the world under the cursor moved from %v to %v", before, after)
+	}
+
+	for range 10 {
+		camera.ZoomAt(300, 100, 2)
+	}
+	if camera.Zoom != MaxZoom {
+		t.Errorf("zoomed in to %v, expected at most %v", camera.Zoom, MaxZoom)
+	}
+	for range 20 {
+		camera.ZoomAt(300, 100, 0.5)
+	}
+	if camera.Zoom != MinZoom {
+		t.Errorf("zoomed out to %v, expected at least %v", camera.Zoom, MinZoom)
+	}
+}
+
+func TestCameraClamp(t *testing.T) {
+	camera := NewCamera(4, 0, 0, 400, 300)
+	camera.SetWorldSize(100, 100)
+
+	// At least half a view of the world stays on screen.
+	camera.Pan(-10000, -10000)
+	if camera.X != -50 || camera.Y != -37.5 {
+		t.Errorf("panned to %v %v, expected -50 -37.5", camera.X, camera.Y)
+	}
+	camera.Pan(10000, 10000)
+	if camera.X != 50 || camera.Y != 62.5 {
+		t.Errorf("panned to %v %v, expected 50 62.5", camera.X, camera.Y)
+	}
+}
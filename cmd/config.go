@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go-falling-sand/game"
+)
+
+// Config holds everything needed to start the game. It is read from an
+// optional xml file, and flags given on the command line take precedence
+// over it.
+type Config struct {
+	XMLName       xml.Name `xml:"config"`
+	Infinite      bool     `xml:"infinite"`
+	WorldWidth    int      `xml:"world-width"`
+	WorldHeight   int      `xml:"world-height"`
+	ChunkWidth    int      `xml:"chunk-width"`
+	ChunkHeight   int      `xml:"chunk-height"`
+	CellSize      float32  `xml:"cell-size"`
+	SideBarLength float32  `xml:"sidebar-width"`
+	DataFolder    string   `xml:"data-folder"`
+	Seed          int64    `xml:"seed"`
+	TPS           int      `xml:"tps"`
+	WindowWidth   int      `xml:"window-width"`
+	WindowHeight  int      `xml:"window-height"`
+	ImportPath    string   `xml:"import"`
+	PalettePath   string   `xml:"palette"`
+
+	// Boundaries are specs as understood by game.SplitBoundary, like "wall",
+	// "wrap", "void" or "source:sand:0.5".
+	BoundaryTop    string `xml:"boundary-top"`
+	BoundaryRight  string `xml:"boundary-right"`
+	BoundaryBottom string `xml:"boundary-bottom"`
+	BoundaryLeft   string `xml:"boundary-left"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		WorldWidth:    8,
+		WorldHeight:   8,
+		ChunkWidth:    10,
+		ChunkHeight:   10,
+		CellSize:      5,
+		SideBarLength: 200,
+		DataFolder:    "./data",
+		TPS:           60,
+		WindowWidth:   DefaultWindowWidth,
+		WindowHeight:  DefaultWindowHeight,
+
+		BoundaryTop:    game.BOUNDARY_WALL,
+		BoundaryRight:  game.BOUNDARY_WALL,
+		BoundaryBottom: game.BOUNDARY_WALL,
+		BoundaryLeft:   game.BOUNDARY_WALL,
+	}
+}
+
+// BoundarySpecs returns the boundary specs indexed by the game.EDGE
+// constants.
+func (config *Config) BoundarySpecs() [game.EDGE_COUNT]*string {
+	return [game.EDGE_COUNT]*string{&config.BoundaryTop, &config.BoundaryRight, &config.BoundaryBottom, &config.BoundaryLeft}
+}
+
+// ApplyBoundaries sets the boundaries of the config on a world.
+func (config *Config) ApplyBoundaries(world *game.World) error {
+	var boundaries [game.EDGE_COUNT]game.Boundary
+	for edge, spec := range config.BoundarySpecs() {
+		boundary, err := world.ParseBoundary(*spec)
+		if err != nil {
+			return fmt.Errorf("%v boundary: %v", game.EDGE_NAMES[edge], err)
+		}
+		boundaries[edge] = boundary
+	}
+	return world.SetBoundaries(boundaries)
+}
+
+// ParseConfig reads the config file named by -config, if any, and then
+// applies the flags that were set explicitly on top of it.
+func ParseConfig(args []string) (Config, error) {
+	config := DefaultConfig()
+	flags, configPath := config.FlagSet()
+	if err := flags.Parse(args); err != nil {
+		return config, err
+	}
+
+	if *configPath != "" {
+		config = DefaultConfig()
+		if err := config.Load(*configPath); err != nil {
+			return config, err
+		}
+
+		// Parse the flags again, now on top of the file.
+		flags, _ = config.FlagSet()
+		if err := flags.Parse(args); err != nil {
+			return config, err
+		}
+	}
+
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
+	return config, config.Validate()
+}
+
+// FlagSet returns flags that write into the config, using its current values
+// as their defaults, along with the -config flag.
+func (config *Config) FlagSet() (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet("falling-sand", flag.ContinueOnError)
+	configPath := flags.String("config", "", "xml file to read the settings from, flags override it")
+	flags.BoolVar(&config.Infinite, "infinite", config.Infinite, "create chunks as they are needed instead of a world of fixed size")
+	flags.IntVar(&config.WorldWidth, "world-width", config.WorldWidth, "width of the world in chunks")
+	flags.IntVar(&config.WorldHeight, "world-height", config.WorldHeight, "height of the world in chunks")
+	flags.IntVar(&config.ChunkWidth, "chunk-width", config.ChunkWidth, "width of a chunk in cells")
+	flags.IntVar(&config.ChunkHeight, "chunk-height", config.ChunkHeight, "height of a chunk in cells")
+	flags.Func("cell-size", fmt.Sprintf("size of a cell in pixels at the default zoom (default %v)", config.CellSize), func(s string) error {
+		_, err := fmt.Sscan(s, &config.CellSize)
+		return err
+	})
+	flags.Func("sidebar-width", fmt.Sprintf("width of the sidebar in pixels (default %v)", config.SideBarLength), func(s string) error {
+		_, err := fmt.Sscan(s, &config.SideBarLength)
+		return err
+	})
+	flags.StringVar(&config.DataFolder, "data", config.DataFolder, "folders to load the element definitions and packs from, separated by "+string(filepath.ListSeparator))
+	flags.Int64Var(&config.Seed, "seed", config.Seed, "seed of the world, 0 picks one from the clock")
+	flags.IntVar(&config.TPS, "tps", config.TPS, "ticks per second")
+	flags.IntVar(&config.WindowWidth, "window-width", config.WindowWidth, "initial width of the window")
+	flags.IntVar(&config.WindowHeight, "window-height", config.WindowHeight, "initial height of the window")
+	flags.StringVar(&config.ImportPath, "import", config.ImportPath, "PNG to paint onto the world at startup")
+	flags.StringVar(&config.PalettePath, "palette", config.PalettePath, "palette file mapping the colours of imported PNGs to elements")
+	for edge, spec := range config.BoundarySpecs() {
+		name := game.EDGE_NAMES[edge]
+		flags.StringVar(spec, "boundary-"+name, *spec, "what happens at the "+name+" edge: wall, wrap, void or source:element[:rate]")
+	}
+	return flags, configPath
+}
+
+// Load reads the settings present in an xml config file, keeping the
+// current values of the ones that are missing.
+func (config *Config) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %v", err)
+	}
+	if err := xml.Unmarshal(data, config); err != nil {
+		return fmt.Errorf("failed to parse config '%v': %v", path, err)
+	}
+	return nil
+}
+
+// Validate reports every setting that the game can't start with.
+func (config *Config) Validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(config.Infinite || config.WorldWidth > 0 && config.WorldHeight > 0, "world size has to be positive, got %vx%v chunks", config.WorldWidth, config.WorldHeight)
+	check(config.ChunkWidth > 0 && config.ChunkHeight > 0, "chunk size has to be positive, got %vx%v cells", config.ChunkWidth, config.ChunkHeight)
+	check(config.ChunkWidth <= 0xFFFF && config.ChunkHeight <= 0xFFFF, "chunk size is too large, got %vx%v cells", config.ChunkWidth, config.ChunkHeight)
+	check(config.CellSize >= MinZoom && config.CellSize <= MaxZoom, "cell size has to be between %v and %v, got %v", MinZoom, MaxZoom, config.CellSize)
+	check(config.SideBarLength >= 0, "sidebar width can't be negative, got %v", config.SideBarLength)
+	check(config.TPS > 0, "tps has to be positive, got %v", config.TPS)
+	check(config.WindowWidth > 0 && config.WindowHeight > 0, "window size has to be positive, got %vx%v", config.WindowWidth, config.WindowHeight)
+
+	var modes [game.EDGE_COUNT]string
+	boundariesValid := true
+	for edge, spec := range config.BoundarySpecs() {
+		mode, _, _, err := game.SplitBoundary(*spec)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%v boundary: %v", game.EDGE_NAMES[edge], err))
+			boundariesValid = false
+			continue
+		}
+		modes[edge] = mode
+		check(!config.Infinite || mode == game.BOUNDARY_WALL, "infinite worlds have no %v edge to put a %v boundary on", game.EDGE_NAMES[edge], mode)
+	}
+	check(
+		!boundariesValid ||
+			(modes[game.EDGE_TOP] == game.BOUNDARY_WRAP) == (modes[game.EDGE_BOTTOM] == game.BOUNDARY_WRAP) &&
+				(modes[game.EDGE_LEFT] == game.BOUNDARY_WRAP) == (modes[game.EDGE_RIGHT] == game.BOUNDARY_WRAP),
+		"wrapping edges have to come in pairs, got top %v, right %v, bottom %v and left %v",
+		modes[game.EDGE_TOP], modes[game.EDGE_RIGHT], modes[game.EDGE_BOTTOM], modes[game.EDGE_LEFT],
+	)
+
+	for _, folder := range filepath.SplitList(config.DataFolder) {
+		if info, err := os.Stat(folder); err != nil {
+			problems = append(problems, fmt.Errorf("data folder: %v", err))
+		} else if !info.IsDir() {
+			problems = append(problems, fmt.Errorf("data folder '%v' is not a folder", folder))
+		}
+	}
+
+	// The board has to have room for at least a chunk next to the sidebar.
+	width := float32(config.WindowWidth) / UIScale
+	height := float32(config.WindowHeight) / UIScale
+	check(
+		width-config.SideBarLength >= float32(config.ChunkWidth)*config.CellSize && height >= float32(config.ChunkHeight)*config.CellSize,
+		"a %vx%v window does not fit the %v pixel sidebar and a chunk of %vx%v cells at cell size %v",
+		config.WindowWidth, config.WindowHeight, config.SideBarLength, config.ChunkWidth, config.ChunkHeight, config.CellSize,
+	)
+	check(height >= ControlsHeight, "a %vx%v window does not fit the sidebar controls", config.WindowWidth, config.WindowHeight)
+
+	return errors.Join(problems...)
+}
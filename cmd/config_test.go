@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		// file is written to a config file passed with -config, if set.
+		file  string
+		args  []string
+		err   string
+		check func(t *testing.T, config Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, config Config) {
+				if config.WorldWidth != 8 || config.ChunkWidth != 10 || config.BoundaryTop != "wall" {
+					t.Errorf("got %+v, expected the defaults", config)
+				}
+			},
+		},
+		{
+			name: "file",
+			file: `<config><world-width>3</world-width><boundary-bottom>void</boundary-bottom></config>`,
+			check: func(t *testing.T, config Config) {
+				if config.WorldWidth != 3 || config.WorldHeight != 8 || config.BoundaryBottom != "void" {
+					t.Errorf("got %+v, expected the file on top of the defaults", config)
+				}
+			},
+		},
+		{
+			name: "flag overrides file",
+			file: `<config><world-width>3</world-width><world-height>4</world-height></config>`,
+			args: []string{"-world-width", "5"},
+			check: func(t *testing.T, config Config) {
+				if config.WorldWidth != 5 || config.WorldHeight != 4 {
+					t.Errorf("got a %vx%v world, expected 5x4", config.WorldWidth, config.WorldHeight)
+				}
+			},
+		},
+		{
+			name: "bad file",
+			file: `<config><world-width>eight</world-width></config>`,
+			err:  "failed to parse config",
+		},
+		{
+			name: "unknown flag",
+			args: []string{"-world-depth", "3"},
+			err:  "flag provided but not defined",
+		},
+		{
+			name: "zero chunk size",
+			args: []string{"-chunk-width", "0"},
+			err:  "chunk size has to be positive",
+		},
+		{
+			name: "chunk too large for the window",
+			args: []string{"-chunk-width", "100"},
+			err:  "window does not fit the 200 pixel sidebar and a chunk of 100x10 cells",
+		},
+		{
+			name: "unpaired wrap",
+			args: []string{"-boundary-left", "wrap"},
+			err:  "wrapping edges have to come in pairs",
+		},
+		{
+			name: "infinite with boundaries",
+			args: []string{"-infinite", "-boundary-bottom", "void"},
+			err:  "infinite worlds have no bottom edge",
+		},
+		{
+			name: "every problem",
+			args: []string{"-tps", "0", "-boundary-top", "lava"},
+			err:  "tps has to be positive, got 0\ntop boundary: unknown boundary 'lava'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := append([]string{"-data", "../data", "-seed", "1"}, test.args...)
+			if test.file != "" {
+				path := filepath.Join(t.TempDir(), "config.xml")
+				if err := os.WriteFile(path, []byte(test.file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, "-config", path)
+			}
+
+			config, err := ParseConfig(args)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, expected %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.check(t, config)
+		})
+	}
+}
+
+func TestParseConfigSeed(t *testing.T) {
+	config, err := ParseConfig([]string{"-data", "../data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Seed == 0 {
+		t.Error("no seed was picked")
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/basicfont"
+
+	"go-falling-sand/game"
+)
+
+// ControlsHeight is how much of the bottom of the sidebar the simulation
+// controls take up.
+const ControlsHeight = 60
+
+type ControlButton struct {
+	Text    func() string
+	Clicked func()
+}
+
+// Controls is the row of buttons at the bottom of the sidebar that pauses,
+// steps and changes the speed of the simulation.
+type Controls struct {
+	X, Y, Width, Height float32
+	Padding             float32
+	Buttons             []ControlButton
+	Clock               *game.Clock
+}
+
+func NewControls(clock *game.Clock, x, width float32) Controls {
+	controls := Controls{}
+
+	controls.Clock = clock
+	controls.X = x
+	controls.Width = width
+	controls.Height = ControlsHeight
+	controls.Padding = 10
+
+	controls.Buttons = []ControlButton{
+		{
+			Text: func() string {
+				if clock.Paused {
+					return ">"
+				}
+				return "||"
+			},
+			Clicked: clock.TogglePause,
+		},
+		{Text: func() string { return ">|" }, Clicked: clock.Step},
+		{Text: func() string { return "-" }, Clicked: clock.Slower},
+		{Text: func() string { return "+" }, Clicked: clock.Faster},
+	}
+
+	return controls
+}
+
+func (controls *Controls) ButtonRect(i int) (x, y, width, height float32) {
+	count := float32(len(controls.Buttons))
+	width = (controls.Width - controls.Padding*(count+1)) / count
+	height = controls.Height/2 - controls.Padding
+	x = controls.X + controls.Padding + float32(i)*(width+controls.Padding)
+	y = controls.Y + controls.Height/2
+	return
+}
+
+func (controls *Controls) GetHoveredButton(x, y float32) int {
+	for i := range controls.Buttons {
+		bx, by, width, height := controls.ButtonRect(i)
+		if x >= bx && x < bx+width && y >= by && y < by+height {
+			return i
+		}
+	}
+	return -1
+}
+
+// Contains reports whether a screen position is on the controls.
+func (controls *Controls) Contains(x, y float32) bool {
+	return x >= controls.X && x < controls.X+controls.Width && y >= controls.Y && y < controls.Y+controls.Height
+}
+
+func (controls *Controls) Update() {
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		x, y := ebiten.CursorPosition()
+		if i := controls.GetHoveredButton(float32(x), float32(y)); i != -1 {
+			controls.Buttons[i].Clicked()
+		}
+	}
+}
+
+func (controls *Controls) Draw(screen *ebiten.Image) {
+	vector.DrawFilledRect(
+		screen,
+		controls.X, controls.Y,
+		controls.Width, controls.Height,
+		color.RGBA{80, 80, 80, 255},
+		true,
+	)
+
+	status := fmt.Sprintf("speed %gx", controls.Clock.Multiplier())
+	if controls.Clock.Paused {
+		status += " (paused)"
+	}
+	drawControlText(screen, status, controls.X+controls.Padding, controls.Y+controls.Padding/2)
+
+	mx, my := ebiten.CursorPosition()
+	hovered := controls.GetHoveredButton(float32(mx), float32(my))
+	for i, button := range controls.Buttons {
+		x, y, width, height := controls.ButtonRect(i)
+
+		background := color.RGBA{120, 120, 120, 255}
+		if i == hovered {
+			background = color.RGBA{150, 150, 150, 255}
+		}
+		vector.DrawFilledRect(screen, x, y, width, height, background, true)
+
+		label := button.Text()
+		drawControlText(screen, label, x+(width-float32(len(label)*7))/2, y+(height-13)/2)
+	}
+}
+
+func drawControlText(screen *ebiten.Image, s string, x, y float32) {
+	drawOptions := text.DrawOptions{}
+	drawOptions.GeoM.Translate(float64(x), float64(y))
+	text.Draw(screen, s, text.NewGoXFace(basicfont.Face7x13), &drawOptions)
+}
+
+// UpdateClock pauses on space, steps a single tick on period and changes the
+// speed with minus and equals.
+func (g *Game) UpdateClock() {
+	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+		g.Clock.TogglePause()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPeriod) {
+		g.Clock.Step()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyMinus) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadSubtract) {
+		g.Clock.Slower()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEqual) || inpututil.IsKeyJustPressed(ebiten.KeyNumpadAdd) {
+		g.Clock.Faster()
+	}
+	g.Controls.Update()
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"go-falling-sand/game"
+)
+
+const (
+	DefaultWindowWidth  = 900
+	DefaultWindowHeight = 600
+
+	// UIScale is how many window pixels one pixel of the layout takes up.
+	UIScale = 1.5
+)
+
+// Game is the ebiten front-end that draws a World and feeds it mouse input.
+type Game struct {
+	World            *game.World
+	Renderer         *Renderer
+	Width, Height    int
+	SideBarLength    float32
+	Camera           *Camera
+	SelectedElement  int
+	Canvas           *game.Canvas
+	Tool             game.Tool
+	Clock            *game.Clock
+	Controls         Controls
+	ElementScrollBar ScrollBar
+	ShowDirty        bool
+	ImportPath       string
+	PalettePath      string
+
+	painting bool
+}
+
+func NewGame(world *game.World, cellSize float32, sideBarLength float32) *Game {
+	g := &Game{}
+
+	g.World = world
+	g.Renderer = NewRenderer(world)
+
+	g.SelectedElement = -1 // No item selected
+	g.Canvas = game.NewCanvas(world)
+	g.Tool = &game.BrushTool{}
+
+	g.SideBarLength = sideBarLength
+	g.Camera = NewCamera(cellSize, sideBarLength, 0, 0, 0)
+	g.Camera.Unbounded = world.Infinite
+	g.Camera.SetWorldSize(world.TotalWidth(), world.TotalHeight())
+
+	g.ElementScrollBar = NewScrollBar(
+		0,
+		sideBarLength,
+		0,
+		30,
+		10,
+		color.RGBA{100, 100, 100, 255},
+		20,
+	)
+
+	g.Clock = game.NewClock()
+	g.Controls = NewControls(g.Clock, 0, sideBarLength)
+
+	for index := range len(world.ElementData) {
+		if data := world.ElementData[index]; data.Selectable {
+			g.AddElementItem(data)
+		}
+	}
+
+	g.Resize(int(DefaultWindowWidth/UIScale), int(DefaultWindowHeight/UIScale))
+
+	return g
+}
+
+func (g *Game) AddElementItem(data *game.ElementData) {
+	index := data.ElementTypeID
+	g.ElementScrollBar.AddItem(ScrollBarItem{
+		Box: &ScrollBarBox{
+			Border:     color.White,
+			Inner:      data.Color,
+			BorderSize: 3,
+		},
+		InnerPadding: 3,
+		TextColor:    color.White,
+		Text:         data.Name,
+		Clicked: func(_ *ScrollBarItem, _ int) error {
+			g.SelectedElement = index
+			return nil
+		},
+		BeforeDraw: func(item *ScrollBarItem, i int) {
+			if g.SelectedElement == index {
+				item.Background = color.RGBA{200, 200, 200, 255}
+			} else if g.ElementScrollBar.GetHovered() == i {
+				item.Background = color.RGBA{150, 150, 150, 255}
+			} else {
+				item.Background = color.Transparent
+			}
+		},
+	})
+}
+
+func (g *Game) Layout(outsizeWidth, outsizeHeight int) (int, int) {
+	width := max(int(float64(outsizeWidth)/UIScale), 1)
+	height := max(int(float64(outsizeHeight)/UIScale), 1)
+	if width != g.Width || height != g.Height {
+		g.Resize(width, height)
+	}
+	return g.Width, g.Height
+}
+
+// Resize lays the sidebar and the board out for a screen of the given size.
+// The sidebar keeps its width and stretches to the full height, and the
+// board takes up the rest.
+func (g *Game) Resize(width, height int) {
+	g.Width, g.Height = width, height
+
+	g.Camera.ViewWidth = max(float32(width)-g.SideBarLength, 0)
+	g.Camera.ViewHeight = float32(height)
+	g.Camera.Clamp()
+
+	g.ElementScrollBar.Height = max(float32(height-ControlsHeight), 0)
+	g.ElementScrollBar.Clamp()
+
+	g.Controls.Y = float32(height - ControlsHeight)
+}
+
+func (g *Game) Draw(screen *ebiten.Image) {
+	screen.Fill(color.Gray{100})
+
+	board := g.Camera.View(screen)
+	g.Renderer.Draw(board, g.Camera.GeoM())
+
+	if g.ShowDirty {
+		g.DrawDirty(board)
+	}
+	g.DrawTool(board)
+
+	if g.Renderer.Heat {
+		if cell, err := g.GetHoveredCell(); err == nil {
+			ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%.1f°", cell.Temperature), int(g.SideBarLength)+4, 4)
+		}
+	}
+
+	g.ElementScrollBar.Draw(screen)
+	g.Controls.Draw(screen)
+}
+
+// DrawDirty outlines the cells that are going to be updated on the next tick.
+func (g *Game) DrawDirty(screen *ebiten.Image) {
+	for _, chunk := range g.World.Chunks {
+		dirty := chunk.NextDirty()
+		if dirty.Empty() {
+			continue
+		}
+
+		offsetX := chunk.X * g.World.ChunkWidth
+		offsetY := chunk.Y * g.World.ChunkHeight
+
+		x, y := g.Camera.WorldToScreen(float32(dirty.MinX+offsetX), float32(dirty.MinY+offsetY))
+		vector.StrokeRect(
+			screen,
+			x,
+			y,
+			float32(dirty.MaxX-dirty.MinX+1)*g.Camera.Zoom,
+			float32(dirty.MaxY-dirty.MinY+1)*g.Camera.Zoom,
+			1,
+			color.RGBA{255, 0, 0, 255},
+			false,
+		)
+	}
+}
+
+func (g *Game) Update() error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyF3) {
+		g.ShowDirty = !g.ShowDirty
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyH) {
+		g.Renderer.SetHeat(!g.Renderer.Heat)
+	}
+	g.UpdateQuickSlots()
+	g.UpdateHistory()
+	g.UpdateClock()
+	g.Camera.Update()
+	g.UpdateImages()
+
+	if err := g.ElementScrollBar.Update(); err != nil {
+		return err
+	}
+	g.World.Focus = g.Camera.VisibleCells()
+	if g.World.Infinite {
+		// Streaming also happens every tick, but the board has to be
+		// filled in while paused too.
+		if err := g.World.Stream(); err != nil {
+			return err
+		}
+	}
+	if err := g.World.Step(g.Clock.Ticks()); err != nil {
+		return err
+	}
+	g.UpdateTools()
+	return nil
+}
+
+func (g *Game) GetHoveredCell() (*game.Cell, error) {
+	mx, my := ebiten.CursorPosition()
+	x := float32(mx)
+	y := float32(my)
+	if !g.Camera.Contains(x, y) {
+		return nil, fmt.Errorf("%v %v is not on board", x, y)
+	}
+	return g.World.GetCell(g.Camera.ScreenToCell(x, y))
+}
+
+// CursorCell returns the world position of the cell under the cursor, even
+// when the cursor is outside of the board.
+func (g *Game) CursorCell() (int, int) {
+	mx, my := ebiten.CursorPosition()
+	return g.Camera.ScreenToCell(float32(mx), float32(my))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"go-falling-sand/game"
+)
+
+// ExportPath is where the P key writes a picture of the world to.
+var ExportPath = filepath.Join(SaveFolder, "world.png")
+
+func (g *Game) ExportPNG(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := g.World.ExportPNG(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// ImportPNG paints a PNG onto the world. If palettePath is empty the
+// element colours themselves are used as the palette.
+func (g *Game) ImportPNG(path, palettePath string) error {
+	var palette game.Palette
+	if palettePath != "" {
+		file, err := os.Open(palettePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if palette, err = g.World.LoadPalette(file); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return g.World.ImportPNG(file, palette)
+}
+
+// UpdateImages exports the world on P, and imports the last import (or the
+// last export) back with shift held.
+func (g *Game) UpdateImages() {
+	if !inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		return
+	}
+
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		path := g.ImportPath
+		if path == "" {
+			path = ExportPath
+		}
+		if err := g.ImportPNG(path, g.PalettePath); err != nil {
+			log.Printf("failed to import '%v': %v", path, err)
+		} else {
+			log.Printf("imported '%v'", path)
+		}
+	} else {
+		if err := g.ExportPNG(ExportPath); err != nil {
+			log.Printf("failed to export '%v': %v", ExportPath, err)
+		} else {
+			log.Printf("exported '%v'", ExportPath)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"log"
+	"os"
 
 	"github.com/hajimehoshi/ebiten/v2"
 
@@ -9,12 +12,59 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		dataFolder := "./data"
+		if len(os.Args) > 2 {
+			dataFolder = os.Args[2]
+		}
+		os.Exit(Validate(dataFolder))
+	}
+
+	config, err := ParseConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
+		log.Fatal(err)
+	}
+
 	ebiten.SetWindowTitle("Falling Sand Game")
-	ebiten.SetWindowSize(game.Dimensions.ScreenWidth, game.Dimensions.ScreenHeight)
+	ebiten.SetWindowSize(config.WindowWidth, config.WindowHeight)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	ebiten.SetTPS(config.TPS)
+
+	var world *game.World
+	if config.Infinite {
+		world, err = game.NewInfiniteWorld(config.ChunkWidth, config.ChunkHeight, config.Seed, config.DataFolder)
+	} else {
+		world, err = game.NewWorld(
+			config.WorldWidth, config.WorldHeight,
+			config.ChunkWidth, config.ChunkHeight,
+			config.Seed,
+			config.DataFolder,
+		)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer world.Close()
 
-	if g, err := game.NewGame(8, 8, 10, 10, 5, 200, "./data"); err != nil {
+	if err := config.ApplyBoundaries(world); err != nil {
 		log.Fatal(err)
-	} else if err := ebiten.RunGame(g); err != nil {
+	}
+
+	g := NewGame(world, config.CellSize, config.SideBarLength)
+	g.Resize(int(float64(config.WindowWidth)/UIScale), int(float64(config.WindowHeight)/UIScale))
+	g.ImportPath = config.ImportPath
+	g.PalettePath = config.PalettePath
+
+	if g.ImportPath != "" {
+		if err := g.ImportPNG(g.ImportPath, g.PalettePath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := ebiten.RunGame(g); err != nil {
+		world.Close()
 		log.Fatal(err)
 	}
 }
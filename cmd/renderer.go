@@ -0,0 +1,96 @@
+package main
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"go-falling-sand/game"
+)
+
+// Renderer keeps a one pixel per cell image of every chunk and only uploads
+// the chunks that changed since the last frame.
+type Renderer struct {
+	World  *game.World
+	Images map[*game.Chunk]*ebiten.Image
+	Heat   bool
+	pixels []byte
+}
+
+func NewRenderer(world *game.World) *Renderer {
+	renderer := &Renderer{}
+
+	renderer.World = world
+	renderer.Images = map[*game.Chunk]*ebiten.Image{}
+	renderer.pixels = make([]byte, world.ChunkArea()*4)
+
+	return renderer
+}
+
+// Refresh uploads every chunk that changed since the last refresh, and drops
+// the images of chunks that are gone.
+func (renderer *Renderer) Refresh() {
+	loaded := make(map[*game.Chunk]bool, len(renderer.World.Chunks))
+	for _, chunk := range renderer.World.Chunks {
+		loaded[chunk] = true
+	}
+	for chunk, img := range renderer.Images {
+		if !loaded[chunk] {
+			img.Deallocate()
+			delete(renderer.Images, chunk)
+		}
+	}
+
+	for _, chunk := range renderer.World.Chunks {
+		img, ok := renderer.Images[chunk]
+		if !ok {
+			img = ebiten.NewImage(renderer.World.ChunkWidth, renderer.World.ChunkHeight)
+			renderer.Images[chunk] = img
+			chunk.MarkModified()
+		}
+		if !chunk.TakeModified() {
+			continue
+		}
+
+		if renderer.Heat {
+			chunk.FillHeatPixels(renderer.pixels)
+		} else {
+			chunk.FillPixels(renderer.pixels)
+		}
+		img.WritePixels(renderer.pixels)
+	}
+}
+
+// SetHeat switches between showing the elements and the heat map.
+func (renderer *Renderer) SetHeat(heat bool) {
+	if renderer.Heat == heat {
+		return
+	}
+	renderer.Heat = heat
+	for _, chunk := range renderer.World.Chunks {
+		chunk.MarkModified()
+	}
+}
+
+// Draw draws the world transformed by geoM, which maps world cells to
+// screen pixels. Chunks that end up outside of screen are skipped.
+func (renderer *Renderer) Draw(screen *ebiten.Image, geoM ebiten.GeoM) {
+	renderer.Refresh()
+
+	view := screen.Bounds()
+	for _, chunk := range renderer.World.Chunks {
+		bounds := chunk.WorldBounds()
+
+		options := ebiten.DrawImageOptions{}
+		options.GeoM.Translate(float64(bounds.MinX), float64(bounds.MinY))
+		options.GeoM.Concat(geoM)
+
+		minX, minY := options.GeoM.Apply(0, 0)
+		maxX, maxY := options.GeoM.Apply(float64(renderer.World.ChunkWidth), float64(renderer.World.ChunkHeight))
+		if !image.Rect(int(minX)-1, int(minY)-1, int(maxX)+1, int(maxY)+1).Overlaps(view) {
+			continue
+		}
+
+		screen.DrawImage(renderer.Images[chunk], &options)
+	}
+}
@@ -1,4 +1,4 @@
-package game
+package main
 
 import (
 	"fmt"
@@ -28,6 +28,7 @@ type ScrollBarItem struct {
 
 type ScrollBar struct {
 	X, Width        float32
+	Height          float32
 	ElementHeight   float32
 	Padding         float32
 	Scroll          float32
@@ -40,11 +41,12 @@ func (s *ScrollBar) GetHovered() int {
 	return s.GetHoveredItem(float32(x), float32(y))
 }
 
-func NewScrollBar(x, width, elementHeight, padding float32, background color.Color, capacity int) ScrollBar {
+func NewScrollBar(x, width, height, elementHeight, padding float32, background color.Color, capacity int) ScrollBar {
 	bar := ScrollBar{}
 
 	bar.X = x
 	bar.Width = width
+	bar.Height = height
 	bar.ElementHeight = elementHeight
 	bar.Padding = padding
 	bar.BackgroundColor = background
@@ -59,6 +61,10 @@ func (s *ScrollBar) AddItem(item ScrollBarItem) {
 }
 
 func (scrollBar *ScrollBar) GetHoveredItem(x, y float32) int {
+	if y < 0 || y >= scrollBar.Height {
+		return -1
+	}
+
 	y += scrollBar.Scroll
 	y += scrollBar.Padding
 
@@ -91,17 +97,22 @@ func (scrollBar *ScrollBar) Move(amt float32, x float32) {
 		return
 	}
 
+	scrollBar.Scroll += amt
+	scrollBar.Clamp()
+}
+
+// Clamp keeps the scroll within the items, for when they or the height of
+// the scroll bar change.
+func (scrollBar *ScrollBar) Clamp() {
 	totalHeight := scrollBar.ElementHeight * float32(len(scrollBar.Items))
 	totalHeight += scrollBar.Padding * float32(len(scrollBar.Items)-1)
 
-	if totalHeight < float32(Dimensions.Height) {
+	if totalHeight < scrollBar.Height {
 		scrollBar.Scroll = 0
 		return
 	}
 
-	scrollBar.Scroll += amt
-
-	maxScroll := totalHeight - float32(Dimensions.Height) + scrollBar.Padding*2
+	maxScroll := totalHeight - scrollBar.Height + scrollBar.Padding*2
 	if scrollBar.Scroll < 0 {
 		scrollBar.Scroll = 0
 	} else if scrollBar.Scroll > maxScroll {
@@ -115,7 +126,7 @@ func (scrollBar *ScrollBar) Draw(screen *ebiten.Image) {
 		scrollBar.X,
 		0,
 		scrollBar.Width,
-		float32(Dimensions.Height),
+		scrollBar.Height,
 		scrollBar.BackgroundColor,
 		true,
 	)
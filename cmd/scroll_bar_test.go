@@ -0,0 +1,36 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestScrollBarClamp(t *testing.T) {
+	bar := NewScrollBar(0, 200, 200, 30, 10, color.Black, 10)
+	for range 10 {
+		bar.AddItem(ScrollBarItem{})
+	}
+
+	// Ten items take up 390 pixels, so 190 of them plus the padding can be
+	// scrolled past.
+	bar.Move(-1000, 100)
+	if bar.Scroll != 210 {
+		t.Errorf("scrolled to %v, expected the end at 210", bar.Scroll)
+	}
+
+	tests := []struct {
+		height float32
+		scroll float32
+	}{
+		{300, 110},
+		{100, 110},
+		{500, 0},
+	}
+	for _, test := range tests {
+		bar.Height = test.height
+		bar.Clamp()
+		if bar.Scroll != test.scroll {
+			t.Errorf("scrolled to %v at a height of %v, expected %v", bar.Scroll, test.height, test.scroll)
+		}
+	}
+}
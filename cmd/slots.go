@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const SaveFolder = "./saves"
+
+// QuickSlotKeys save the world into the matching quick slot, or load it back
+// while shift is held.
+var QuickSlotKeys = []ebiten.Key{ebiten.KeyF5, ebiten.KeyF6, ebiten.KeyF7, ebiten.KeyF8}
+
+func QuickSlotPath(slot int) string {
+	return filepath.Join(SaveFolder, fmt.Sprintf("slot%v.sav", slot))
+}
+
+func (g *Game) SaveSlot(slot int) error {
+	if err := os.MkdirAll(SaveFolder, 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(QuickSlotPath(slot))
+	if err != nil {
+		return err
+	}
+
+	if err := g.World.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (g *Game) LoadSlot(slot int) error {
+	file, err := os.Open(QuickSlotPath(slot))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := g.World.Load(file); err != nil {
+		return err
+	}
+	g.Canvas.History.Clear()
+	g.Camera.Unbounded = g.World.Infinite
+	g.Camera.SetWorldSize(g.World.TotalWidth(), g.World.TotalHeight())
+
+	heat := g.Renderer.Heat
+	g.Renderer = NewRenderer(g.World)
+	g.Renderer.SetHeat(heat)
+	return nil
+}
+
+func (g *Game) UpdateQuickSlots() {
+	shift := ebiten.IsKeyPressed(ebiten.KeyShift)
+	for i, key := range QuickSlotKeys {
+		if !inpututil.IsKeyJustPressed(key) {
+			continue
+		}
+
+		slot := i + 1
+		if shift {
+			if err := g.LoadSlot(slot); err != nil {
+				log.Printf("failed to load slot %v: %v", slot, err)
+			} else {
+				log.Printf("loaded slot %v", slot)
+			}
+		} else {
+			if err := g.SaveSlot(slot); err != nil {
+				log.Printf("failed to save slot %v: %v", slot, err)
+			} else {
+				log.Printf("saved slot %v", slot)
+			}
+		}
+	}
+}
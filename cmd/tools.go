@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"go-falling-sand/game"
+)
+
+// ToolKeys select the matching tool.
+var ToolKeys = []struct {
+	Key  ebiten.Key
+	Tool game.Tool
+}{
+	{ebiten.KeyB, &game.BrushTool{}},
+	{ebiten.KeyL, &game.LineTool{}},
+	{ebiten.KeyR, &game.RectTool{}},
+	{ebiten.KeyG, &game.FillTool{}},
+}
+
+// UpdateTools switches tools and brushes, and feeds the mouse to the
+// selected tool. Tab toggles the brush shape and shift+wheel changes its
+// radius.
+func (g *Game) UpdateTools() {
+	for _, binding := range ToolKeys {
+		if inpututil.IsKeyJustPressed(binding.Key) && !g.painting {
+			g.Tool = binding.Tool
+		}
+	}
+
+	brush := &g.Canvas.Brush
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		if brush.Shape == game.BRUSH_CIRCLE {
+			brush.Shape = game.BRUSH_SQUARE
+		} else {
+			brush.Shape = game.BRUSH_CIRCLE
+		}
+	}
+	dx, dy := ebiten.Wheel()
+	if dy == 0 {
+		// Some platforms turn shift+wheel into horizontal scrolling.
+		dy = dx
+	}
+	if dy != 0 && ebiten.IsKeyPressed(ebiten.KeyShift) {
+		if dy > 0 {
+			brush.Radius++
+		} else {
+			brush.Radius--
+		}
+		brush.Radius = max(0, min(brush.Radius, game.MAX_BRUSH_RADIUS))
+	}
+
+	x, y := g.CursorCell()
+	switch {
+	case g.painting && !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft):
+		g.painting = false
+		g.Tool.End(g.Canvas, x, y)
+		g.Canvas.EndEdit()
+	case g.painting:
+		g.Tool.Move(g.Canvas, x, y)
+	case inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && g.SelectedElement != -1:
+		if _, err := g.GetHoveredCell(); err != nil {
+			return
+		}
+		g.painting = true
+		g.Canvas.Element = g.SelectedElement
+		g.Canvas.BeginEdit()
+		g.Tool.Begin(g.Canvas, x, y)
+	}
+}
+
+// UpdateHistory undoes the last edit on ctrl+Z and redoes it on ctrl+Y or
+// ctrl+shift+Z.
+func (g *Game) UpdateHistory() {
+	if g.painting || !ebiten.IsKeyPressed(ebiten.KeyControl) {
+		return
+	}
+
+	shift := ebiten.IsKeyPressed(ebiten.KeyShift)
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyZ) && !shift:
+		g.Canvas.Undo()
+	case inpututil.IsKeyJustPressed(ebiten.KeyY), inpututil.IsKeyJustPressed(ebiten.KeyZ) && shift:
+		g.Canvas.Redo()
+	}
+}
+
+// DrawTool shows what the current tool is about to paint, the outline of the
+// brush under the cursor and which tool is selected.
+func (g *Game) DrawTool(screen *ebiten.Image) {
+	previewColor := color.RGBA{100, 100, 100, 100}
+	if data, ok := g.World.ElementData[g.SelectedElement]; ok {
+		r, gr, b, _ := data.Color.RGBA()
+		previewColor = color.RGBA{uint8(r >> 9), uint8(gr >> 9), uint8(b >> 9), 128}
+	}
+	g.Tool.Preview(g.Canvas, func(x, y int) {
+		sx, sy := g.Camera.WorldToScreen(float32(x), float32(y))
+		vector.DrawFilledRect(
+			screen,
+			sx,
+			sy,
+			g.Camera.Zoom,
+			g.Camera.Zoom,
+			previewColor,
+			false,
+		)
+	})
+
+	if _, err := g.GetHoveredCell(); err == nil {
+		x, y := g.CursorCell()
+		brush := g.Canvas.Brush
+		size := float32(brush.Radius*2+1) * g.Camera.Zoom
+		left, top := g.Camera.WorldToScreen(float32(x-brush.Radius), float32(y-brush.Radius))
+		if brush.Shape == game.BRUSH_CIRCLE {
+			vector.StrokeCircle(screen, left+size/2, top+size/2, size/2, 1, color.White, true)
+		} else {
+			vector.StrokeRect(screen, left, top, size, size, 1, color.White, false)
+		}
+	}
+
+	ebitenutil.DebugPrintAt(
+		screen,
+		fmt.Sprintf("%v %v r%v", g.Tool.Name(), g.Canvas.Brush.Shape, g.Canvas.Brush.Radius),
+		int(g.SideBarLength)+4,
+		int(g.Camera.ViewY+g.Camera.ViewHeight)-20,
+	)
+}
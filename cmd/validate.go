@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"go-falling-sand/game"
+)
+
+// Validate checks every element definition in dataFolder and prints all the
+// problems found in them. It returns the exit code of the validate command.
+func Validate(dataFolder string) int {
+	count, err := game.ValidateElements(dataFolder)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("%v: ok, %v elements\n", dataFolder, count)
+	return 0
+}
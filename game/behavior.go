@@ -1,7 +1,9 @@
 package game
 
+import "math/rand"
+
 type ElementKind interface {
 	Create(cell *Cell) error
-	Update(cell *Cell) error
+	Update(cell *Cell, rng *rand.Rand) error
 	IsA(kind string) bool
 }
@@ -0,0 +1,241 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	EDGE_TOP = iota
+	EDGE_RIGHT
+	EDGE_BOTTOM
+	EDGE_LEFT
+	EDGE_COUNT
+)
+
+var EDGE_NAMES = [EDGE_COUNT]string{"top", "right", "bottom", "left"}
+
+const (
+	// BOUNDARY_WALL lines the edge with walls, so cells stop there.
+	BOUNDARY_WALL = "wall"
+	// BOUNDARY_WRAP connects the edge to the opposite one, which has to wrap
+	// as well.
+	BOUNDARY_WRAP = "wrap"
+	// BOUNDARY_VOID deletes whatever reaches the outermost row of cells.
+	BOUNDARY_VOID = "void"
+	// BOUNDARY_SOURCE keeps spawning an element in the outermost row of
+	// cells wherever there is air.
+	BOUNDARY_SOURCE = "source"
+)
+
+// BOUNDARY_MODES lists every mode, in the order saves refer to them by.
+var BOUNDARY_MODES = []string{BOUNDARY_WALL, BOUNDARY_WRAP, BOUNDARY_VOID, BOUNDARY_SOURCE}
+
+// Boundary is what happens at one edge of a world of fixed size. Element and
+// Rate are only used by sources: Rate is the chance per tick that an air
+// cell on the edge turns into Element.
+type Boundary struct {
+	Mode    string
+	Element int
+	Rate    float32
+}
+
+// WallBoundaries returns walls for every edge, which is what worlds start
+// out with.
+func WallBoundaries() [EDGE_COUNT]Boundary {
+	var boundaries [EDGE_COUNT]Boundary
+	for edge := range boundaries {
+		boundaries[edge] = Boundary{Mode: BOUNDARY_WALL}
+	}
+	return boundaries
+}
+
+// SplitBoundary splits a boundary spec like "wall", "wrap", "void",
+// "source:sand" or "source:basic:sand:0.25" into its parts, without looking
+// up the element. A last part that is a number is the rate, as element names
+// can contain the pack separator themselves.
+func SplitBoundary(spec string) (mode, element string, rate float32, err error) {
+	parts := strings.Split(spec, ":")
+	mode = parts[0]
+
+	switch mode {
+	case BOUNDARY_WALL, BOUNDARY_WRAP, BOUNDARY_VOID:
+		if len(parts) > 1 {
+			return "", "", 0, fmt.Errorf("boundary '%v' takes no arguments", mode)
+		}
+	case BOUNDARY_SOURCE:
+		if len(parts) < 2 || parts[1] == "" {
+			return "", "", 0, fmt.Errorf("expected 'source:element' or 'source:element:rate', got '%v'", spec)
+		}
+		parts = parts[1:]
+		rate = 1
+		if value, err := strconv.ParseFloat(parts[len(parts)-1], 32); len(parts) > 1 && err == nil {
+			if value <= 0 || value > 1 {
+				return "", "", 0, fmt.Errorf("rate of '%v' has to be a number between 0 and 1", spec)
+			}
+			rate = float32(value)
+			parts = parts[:len(parts)-1]
+		}
+		element = strings.Join(parts, ":")
+	default:
+		return "", "", 0, fmt.Errorf("unknown boundary '%v', expected one of %v", mode, strings.Join(BOUNDARY_MODES, ", "))
+	}
+	return mode, element, rate, nil
+}
+
+// ParseBoundary turns a boundary spec, see SplitBoundary, into a Boundary.
+func (w *World) ParseBoundary(spec string) (Boundary, error) {
+	mode, element, rate, err := SplitBoundary(spec)
+	if err != nil {
+		return Boundary{}, err
+	}
+	boundary := Boundary{Mode: mode, Rate: rate}
+	if mode == BOUNDARY_SOURCE {
+		id, ok := w.LookupElement("", element)
+		if !ok {
+			return Boundary{}, fmt.Errorf("there is no element named '%v'", element)
+		}
+		boundary.Element = id
+	}
+	return boundary, nil
+}
+
+// SetBoundaries changes what happens at the edges of the world, repainting
+// the outermost cells: walls go up along wall edges and come down elsewhere.
+func (w *World) SetBoundaries(boundaries [EDGE_COUNT]Boundary) error {
+	if err := w.setBoundaries(boundaries); err != nil {
+		return err
+	}
+
+	if w.Infinite {
+		return nil
+	}
+
+	for _, chunk := range w.Chunks {
+		for i := range chunk.Cells {
+			cell := &chunk.Cells[i]
+			x, y := cell.WorldX(), cell.WorldY()
+			if !w.OnEdge(x, y) {
+				continue
+			}
+			boundary, _ := w.BoundaryAt(x, y)
+			if boundary.Mode == BOUNDARY_WALL {
+				cell.Place(w.WallElement)
+			} else if cell.Type == w.WallElement {
+				cell.Place(w.AirElement)
+			} else {
+				cell.MarkChanged()
+			}
+		}
+	}
+	return nil
+}
+
+// setBoundaries checks and stores boundaries without touching any cells.
+func (w *World) setBoundaries(boundaries [EDGE_COUNT]Boundary) error {
+	for edge, boundary := range boundaries {
+		if w.Infinite && boundary.Mode != BOUNDARY_WALL {
+			return fmt.Errorf("infinite worlds have no %v edge", EDGE_NAMES[edge])
+		}
+		if !slices.Contains(BOUNDARY_MODES, boundary.Mode) {
+			return fmt.Errorf("unknown boundary '%v' on the %v edge", boundary.Mode, EDGE_NAMES[edge])
+		}
+		if _, ok := w.ElementData[boundary.Element]; boundary.Mode == BOUNDARY_SOURCE && !ok {
+			return fmt.Errorf("the %v edge is a source of element %v, which does not exist", EDGE_NAMES[edge], boundary.Element)
+		}
+		opposite := (edge + 2) % EDGE_COUNT
+		if (boundary.Mode == BOUNDARY_WRAP) != (boundaries[opposite].Mode == BOUNDARY_WRAP) {
+			return fmt.Errorf("the %v edge wraps, so the %v edge has to wrap too", EDGE_NAMES[edge], EDGE_NAMES[opposite])
+		}
+	}
+
+	w.Boundaries = boundaries
+	w.wrapX = boundaries[EDGE_LEFT].Mode == BOUNDARY_WRAP
+	w.wrapY = boundaries[EDGE_TOP].Mode == BOUNDARY_WRAP
+	w.activeBoundaries = false
+	for _, boundary := range boundaries {
+		if boundary.Mode == BOUNDARY_VOID || boundary.Mode == BOUNDARY_SOURCE {
+			w.activeBoundaries = true
+		}
+	}
+	return nil
+}
+
+// OnEdge reports whether a world position is one of the outermost cells of a
+// world of fixed size.
+func (w *World) OnEdge(worldX, worldY int) bool {
+	return !w.Infinite && (worldX == 0 || worldY == 0 || worldX == w.TotalWidth()-1 || worldY == w.TotalHeight()-1)
+}
+
+// BoundaryAt returns the boundary an outermost cell of the world belongs to.
+// Cells in a corner belong to both edges, walls win over sources and sources
+// over voids. Cells on wrapping edges aren't part of any boundary.
+func (w *World) BoundaryAt(worldX, worldY int) (Boundary, bool) {
+	if !w.OnEdge(worldX, worldY) {
+		return Boundary{}, false
+	}
+
+	edges := make([]int, 0, 2)
+	if worldY == 0 {
+		edges = append(edges, EDGE_TOP)
+	}
+	if worldX == w.TotalWidth()-1 {
+		edges = append(edges, EDGE_RIGHT)
+	}
+	if worldY == w.TotalHeight()-1 {
+		edges = append(edges, EDGE_BOTTOM)
+	}
+	if worldX == 0 {
+		edges = append(edges, EDGE_LEFT)
+	}
+
+	for _, mode := range []string{BOUNDARY_WALL, BOUNDARY_SOURCE, BOUNDARY_VOID} {
+		for _, edge := range edges {
+			if w.Boundaries[edge].Mode == mode {
+				return w.Boundaries[edge], true
+			}
+		}
+	}
+	return Boundary{}, false
+}
+
+// wrap moves a world position that fell off a wrapping edge back in on the
+// other side.
+func (w *World) wrap(worldX, worldY int) (int, int) {
+	if w.wrapX {
+		width := w.TotalWidth()
+		worldX = ((worldX % width) + width) % width
+	}
+	if w.wrapY {
+		height := w.TotalHeight()
+		worldY = ((worldY % height) + height) % height
+	}
+	return worldX, worldY
+}
+
+// UpdateBoundary applies void and source edges to the cell. It returns true
+// if the cell shouldn't update any further this tick.
+func (cell *Cell) UpdateBoundary(rng *rand.Rand) bool {
+	world := cell.World()
+	boundary, ok := world.BoundaryAt(cell.WorldX(), cell.WorldY())
+	if !ok {
+		return false
+	}
+
+	switch boundary.Mode {
+	case BOUNDARY_VOID:
+		if cell.Type != world.AirElement {
+			cell.Place(world.AirElement)
+		}
+		return true
+	case BOUNDARY_SOURCE:
+		cell.KeepAwake()
+		if cell.Type == world.AirElement && rng.Float32() < boundary.Rate {
+			cell.Place(boundary.Element)
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package game
+
+import "testing"
+
+func TestSplitBoundary(t *testing.T) {
+	tests := []struct {
+		spec    string
+		mode    string
+		element string
+		rate    float32
+		valid   bool
+	}{
+		{"wall", BOUNDARY_WALL, "", 0, true},
+		{"wrap:sand", "", "", 0, false},
+		{"source:sand", BOUNDARY_SOURCE, "sand", 1, true},
+		{"source:sand:0.25", BOUNDARY_SOURCE, "sand", 0.25, true},
+		{"source:basic:sand", BOUNDARY_SOURCE, "basic:sand", 1, true},
+		{"source:basic:sand:0.5", BOUNDARY_SOURCE, "basic:sand", 0.5, true},
+		{"source:sand:2", "", "", 0, false},
+		{"source:", "", "", 0, false},
+		{"lava", "", "", 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			mode, element, rate, err := SplitBoundary(test.spec)
+			if (err == nil) != test.valid {
+				t.Fatalf("got error %v", err)
+			}
+			if mode != test.mode || element != test.element || rate != test.rate {
+				t.Errorf("got %v %v %v, expected %v %v %v", mode, element, rate, test.mode, test.element, test.rate)
+			}
+		})
+	}
+}
+
+func TestParseBoundary(t *testing.T) {
+	world := newTestWorld(t, 1, 1, 10, 10)
+
+	boundary, err := world.ParseBoundary("source:sand:0.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Boundary{BOUNDARY_SOURCE, lookup(t, world, "sand"), 0.5}); boundary != expected {
+		t.Errorf("got %v, expected %v", boundary, expected)
+	}
+	if _, err := world.ParseBoundary("source:lava"); err == nil {
+		t.Error("expected an error for a source of an unknown element")
+	}
+}
+
+func TestBoundaries(t *testing.T) {
+	tests := []struct {
+		name       string
+		boundaries map[int]string
+		// sand is painted into rect before stepping. After every tick all
+		// of it is counted, and so is the sand in the top row of cells.
+		rect     Rect
+		ticks    int
+		expected func(t *testing.T, counts, top []int)
+	}{
+		{
+			name:       "walls",
+			boundaries: map[int]string{},
+			rect:       Rect{5, 5, 14, 8},
+			ticks:      40,
+			expected: func(t *testing.T, counts, top []int) {
+				for tick, count := range counts {
+					if count != 40 || top[tick] != 0 {
+						t.Fatalf("%v cells of sand, %v of them at the top after %v ticks, expected walls to keep all 40 at the bottom", count, top[tick], tick+1)
+					}
+				}
+			},
+		},
+		{
+			name:       "sand falls into the void",
+			boundaries: map[int]string{EDGE_BOTTOM: "void"},
+			rect:       Rect{5, 5, 14, 8},
+			ticks:      40,
+			expected: func(t *testing.T, counts, top []int) {
+				if counts[len(counts)-1] != 0 {
+					t.Errorf("%v cells of sand left, expected all of it to fall through", counts[len(counts)-1])
+				}
+			},
+		},
+		{
+			name:       "a source keeps emitting",
+			boundaries: map[int]string{EDGE_TOP: "source:sand:0.5", EDGE_BOTTOM: "void"},
+			rect:       Rect{},
+			ticks:      60,
+			expected: func(t *testing.T, counts, top []int) {
+				for tick := 10; tick < len(counts); tick++ {
+					if counts[tick] < 5 {
+						t.Fatalf("%v cells of sand after %v ticks, expected the source to keep up a stream", counts[tick], tick+1)
+					}
+				}
+			},
+		},
+		{
+			name:       "cells cross a wrapping edge",
+			boundaries: map[int]string{EDGE_TOP: "wrap", EDGE_BOTTOM: "wrap"},
+			rect:       Rect{5, 5, 14, 8},
+			ticks:      40,
+			expected: func(t *testing.T, counts, top []int) {
+				crossed := false
+				for tick, count := range counts {
+					if count != 40 {
+						t.Fatalf("%v cells of sand after %v ticks, expected all 40 to keep falling", count, tick+1)
+					}
+					crossed = crossed || top[tick] > 0
+				}
+				if !crossed {
+					t.Error("no sand came in at the top")
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			world := newTestWorld(t, 2, 2, 10, 10)
+			boundaries := WallBoundaries()
+			for edge, spec := range test.boundaries {
+				boundary, err := world.ParseBoundary(spec)
+				if err != nil {
+					t.Fatal(err)
+				}
+				boundaries[edge] = boundary
+			}
+			if err := world.SetBoundaries(boundaries); err != nil {
+				t.Fatal(err)
+			}
+
+			sand := lookup(t, world, "sand")
+			if test.rect != (Rect{}) {
+				canvas := NewCanvas(world)
+				canvas.Element = sand
+				canvas.FillRect(test.rect)
+			}
+
+			counts, top := make([]int, test.ticks), make([]int, test.ticks)
+			for tick := range counts {
+				if err := world.Step(1); err != nil {
+					t.Fatal(err)
+				}
+				counts[tick] = countCells(world, sand)
+				for x := range world.TotalWidth() {
+					if cell, _ := world.GetCell(x, 0); cell.Type == sand {
+						top[tick]++
+					}
+				}
+			}
+			test.expected(t, counts, top)
+		})
+	}
+}
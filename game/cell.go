@@ -2,32 +2,41 @@ package game
 
 import (
 	"errors"
+	"math/rand"
 )
 
 type Cell struct {
 	X, Y        int
 	Type        int
+	Temperature float32
 	Chunk       *Chunk
-	Data        *[]int
-	UpdateCycle bool
+	UpdatedAt   uint64 // Tick+1 of the last tick the cell updated in, 0 if never
+	BornAt      uint64 // Tick the cell turned into its current element
 }
 
 func (cell *Cell) HasUpdated() bool {
-	return cell.UpdateCycle != cell.Game().UpdateCycle
+	return cell.UpdatedAt == cell.World().Tick+1
 }
 
-func (cell *Cell) Update() error {
+func (cell *Cell) Update(rng *rand.Rand) error {
 	if cell.HasUpdated() {
 		return nil
 	}
-	cell.UpdateCycle = !cell.UpdateCycle
+	cell.UpdatedAt = cell.World().Tick + 1
+	if cell.World().activeBoundaries && cell.UpdateBoundary(rng) {
+		return nil
+	}
+	cell.Conduct()
+	if cell.Transition() {
+		return nil
+	}
 	kind := cell.ElementData().Kind
-	if err := kind.Update(cell); err != nil {
+	if err := kind.Update(cell, rng); err != nil {
 		return nil
 	}
 
 	for _, kind := range cell.ElementData().OtherKinds {
-		if err := kind.Update(cell); err != nil {
+		if err := kind.Update(cell, rng); err != nil {
 			return err
 		}
 	}
@@ -36,39 +45,57 @@ func (cell *Cell) Update() error {
 }
 
 func (cell *Cell) GetCell(relativeX, relativeY int) (*Cell, error) {
-	return cell.Game().GetCell(cell.WorldX()+relativeX, cell.WorldY()+relativeY)
+	world := cell.World()
+	// Most neighbours are in the same chunk, which saves looking it up.
+	x, y := cell.X+relativeX, cell.Y+relativeY
+	if x >= 0 && y >= 0 && x < world.ChunkWidth && y < world.ChunkHeight {
+		return &cell.Chunk.Cells[world.CalculateCellIndex(x, y)], nil
+	}
+	return world.GetCell(cell.WorldX()+relativeX, cell.WorldY()+relativeY)
 }
 
-func (cell *Cell) Game() *Game {
-	return cell.Chunk.Game
+func (cell *Cell) World() *World {
+	return cell.Chunk.World
 }
 
 func (cell *Cell) WorldX() int {
-	return cell.X + cell.Chunk.X*cell.Game().ChunkWidth
+	return cell.X + cell.Chunk.X*cell.World().ChunkWidth
 }
 
 func (cell *Cell) WorldY() int {
-	return cell.Y + cell.Chunk.Y*cell.Game().ChunkHeight
+	return cell.Y + cell.Chunk.Y*cell.World().ChunkHeight
+}
+
+// Age returns how many ticks ago the cell turned into its current element.
+func (cell *Cell) Age() uint64 {
+	return cell.World().Tick - cell.BornAt
 }
 
 func (cell *Cell) ElementData() *ElementData {
-	return cell.Game().ElementData[cell.Type]
+	return cell.World().ElementData[cell.Type]
 }
 
 func (cell *Cell) Index() int {
-	return cell.Game().CalculateCellIndex(cell.X, cell.Y)
+	return cell.World().CalculateCellIndex(cell.X, cell.Y)
 }
 
-func (cell *Cell) Switch(other *Cell) (*Cell, error) {
+func (cell *Cell) Switch(other *Cell, rng *rand.Rand) (*Cell, error) {
 	if other == nil {
 		return nil, errors.New("can't switch places with nil cell")
 	}
 
-	cell.Type, other.Type = other.Type, cell.Type
-	cell.UpdateCycle, other.UpdateCycle = other.UpdateCycle, cell.UpdateCycle
+	if cell.Type != other.Type {
+		cell.Type, other.Type = other.Type, cell.Type
+		cell.MarkChanged()
+		other.MarkChanged()
+	}
+	cell.Temperature, other.Temperature = other.Temperature, cell.Temperature
+	cell.swapData(other)
+	cell.BornAt, other.BornAt = other.BornAt, cell.BornAt
+	cell.UpdatedAt, other.UpdatedAt = other.UpdatedAt, cell.UpdatedAt
 
 	if !cell.HasUpdated() {
-		err := cell.Update()
+		err := cell.Update(rng)
 		if err != nil {
 			return nil, err
 		}
@@ -82,7 +109,15 @@ func (cell *Cell) CanFallInto(other *Cell) bool {
 }
 
 func (cell *Cell) CanMoveInto(other *Cell) bool {
-	return cell.CanFallInto(other) && !other.HasUpdated()
+	if !cell.CanFallInto(other) {
+		return false
+	}
+	if other.HasUpdated() {
+		// The way is only blocked for this tick, so try again on the next one.
+		cell.KeepAwake()
+		return false
+	}
+	return true
 }
 
 func (cell *Cell) IsA(kind string) bool {
@@ -92,3 +127,55 @@ func (cell *Cell) IsA(kind string) bool {
 func (cell *Cell) IsSolid() bool {
 	return cell.IsA("Solid")
 }
+
+// SetType turns the cell into another element and wakes everything around it.
+// The cell keeps its temperature, but its properties start over from the
+// defaults of the new element.
+func (cell *Cell) SetType(elementType int) {
+	if cell.Type == elementType {
+		return
+	}
+	cell.Type = elementType
+	cell.BornAt = cell.World().Tick
+	cell.ResetData()
+	cell.MarkChanged()
+}
+
+// Place replaces the cell with a brand new cell of the given element, as if
+// it had been painted there.
+func (cell *Cell) Place(elementType int) {
+	cell.SetType(elementType)
+	cell.BornAt = cell.World().Tick
+	cell.ResetData()
+	cell.SetTemperature(cell.ElementData().Temperature)
+}
+
+// MarkChanged wakes the cell and its neighbours up for the next tick.
+func (cell *Cell) MarkChanged() {
+	cell.Chunk.modified.Store(true)
+	x, y := cell.WorldX(), cell.WorldY()
+	cell.World().WakeArea(Rect{x - 1, y - 1, x + 1, y + 1})
+}
+
+// KeepAwake makes sure the cell gets updated on the next tick even though it
+// did not change. Cells whose behaviour depends on chance call it so their
+// chunk doesn't fall asleep under them.
+func (cell *Cell) KeepAwake() {
+	x, y := cell.WorldX(), cell.WorldY()
+	cell.World().WakeArea(Rect{x, y, x, y})
+}
+
+// HasNeighbour reports whether any of the eight surrounding cells matches.
+func (cell *Cell) HasNeighbour(match func(other *Cell) bool) bool {
+	for x := -1; x <= 1; x++ {
+		for y := -1; y <= 1; y++ {
+			if x == 0 && y == 0 {
+				continue
+			}
+			if other, err := cell.GetCell(x, y); err == nil && match(other) {
+				return true
+			}
+		}
+	}
+	return false
+}
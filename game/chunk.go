@@ -3,54 +3,84 @@ package game
 import (
 	"fmt"
 	"go-falling-sand/util"
-
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
+	"math/rand"
+	"sync"
+	"sync/atomic"
 )
 
 type Chunk struct {
 	X, Y      int
-	Game      *Game
+	World     *World
 	Cells     []Cell
 	CellOrder []int
+	Rand      *rand.Rand
+
+	// Data holds the property values of the cells, World.DataSlots of them
+	// per cell in the order of Cells.
+	Data []int32
+
+	// Dirty holds the cells being updated during the current tick. Cells
+	// that change wake their surroundings up for the next tick, and a chunk
+	// with nothing to wake up is asleep and costs nothing to update.
+	Dirty     Rect
+	nextDirty Rect
+	// laterDirty holds the cells woken up by neighbouring chunks before the
+	// chunk updated in the current tick, see Wake.
+	laterDirty Rect
+	dirtyLock  sync.Mutex
+	// tickedAt is Tick+1 of the last tick the chunk updated in.
+	tickedAt uint64
+
+	// modified is set whenever a cell of the chunk changes and cleared by
+	// whoever draws it.
+	modified atomic.Bool
 }
 
-func NewChunk(game *Game, x, y int) Chunk {
-	chunk := Chunk{}
+func NewChunk(world *World, x, y int) *Chunk {
+	chunk := &Chunk{}
 
-	chunk.Game = game
+	chunk.World = world
 
 	chunk.X = x
 	chunk.Y = y
 
-	chunk.Cells = make([]Cell, game.ChunkArea())
-	chunk.CellOrder = make([]int, game.ChunkArea())
+	chunk.Rand = rand.New(rand.NewSource(chunk.Seed()))
+
+	chunk.Cells = make([]Cell, world.ChunkArea())
+	chunk.CellOrder = make([]int, 0, world.ChunkArea())
+	chunk.Data = make([]int32, world.ChunkArea()*world.DataSlots)
 
-	for x := range game.ChunkWidth {
-		for y := range game.ChunkHeight {
+	chunk.Dirty = EmptyRect()
+	chunk.nextDirty = chunk.Bounds()
+	chunk.laterDirty = EmptyRect()
+	chunk.modified.Store(true)
 
-			i := game.CalculateCellIndex(x, y)
+	for x := range world.ChunkWidth {
+		for y := range world.ChunkHeight {
 
-			chunk.CellOrder[i] = i
+			i := world.CalculateCellIndex(x, y)
 
 			var cellType int
 
-			worldX := x + chunk.X*game.ChunkWidth
-			worldY := y + chunk.Y*game.ChunkHeight
+			worldX := x + chunk.X*world.ChunkWidth
+			worldY := y + chunk.Y*world.ChunkHeight
 
-			if worldX == 0 || worldY == 0 || worldX == game.TotalWidth()-1 || worldY == game.TotalHeight()-1 {
-				cellType = game.WallElement
+			if boundary, ok := world.BoundaryAt(worldX, worldY); ok && boundary.Mode == BOUNDARY_WALL {
+				cellType = world.WallElement
 			} else {
-				cellType = game.AirElement
+				cellType = world.AirElement
 			}
 
 			cell := Cell{
 				X: x, Y: y,
-				Type:  cellType,
-				Chunk: &chunk,
+				Type:        cellType,
+				Temperature: world.ElementData[cellType].Temperature,
+				Chunk:       chunk,
+				BornAt:      world.Tick,
 			}
 
 			chunk.Cells[i] = cell
+			chunk.Cells[i].ResetData()
 		}
 	}
 
@@ -58,40 +88,101 @@ func NewChunk(game *Game, x, y int) Chunk {
 }
 
 func (chunk *Chunk) GetCell(cellX, cellY int) (*Cell, error) {
-	if cellX < 0 || cellY < 0 || cellX >= chunk.Game.ChunkWidth || cellY >= chunk.Game.ChunkHeight {
+	if cellX < 0 || cellY < 0 || cellX >= chunk.World.ChunkWidth || cellY >= chunk.World.ChunkHeight {
 		return nil, fmt.Errorf("there is no cell in chunk at local position %v %v", cellX, cellY)
 	}
-	return &chunk.Cells[chunk.Game.CalculateCellIndex(cellX, cellY)], nil
+	return &chunk.Cells[chunk.World.CalculateCellIndex(cellX, cellY)], nil
 }
 
-func (chunk *Chunk) Update() error {
-	util.Shuffle(chunk.CellOrder)
-	for i := range chunk.CellOrder {
-		i = chunk.CellOrder[i]
+func (chunk *Chunk) Update(rng *rand.Rand) error {
+	chunk.CellOrder = chunk.CellOrder[:0]
+	for y := chunk.Dirty.MinY; y <= chunk.Dirty.MaxY; y++ {
+		for x := chunk.Dirty.MinX; x <= chunk.Dirty.MaxX; x++ {
+			chunk.CellOrder = append(chunk.CellOrder, chunk.World.CalculateCellIndex(x, y))
+		}
+	}
+
+	util.Shuffle(rng, chunk.CellOrder)
+	for _, i := range chunk.CellOrder {
 		cell := &chunk.Cells[i]
-		if err := cell.Update(); err != nil {
+		if err := cell.Update(rng); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (chunk *Chunk) Draw(screen *ebiten.Image) {
-	for x := range chunk.Game.ChunkWidth {
-		for y := range chunk.Game.ChunkHeight {
-			i := chunk.Game.CalculateCellIndex(x, y)
-
-			cell := chunk.Cells[i]
-
-			vector.DrawFilledRect(
-				screen,
-				float32(x+chunk.X*chunk.Game.ChunkWidth)*chunk.Game.CellSize+chunk.Game.SideBarLength,
-				float32(y+chunk.Y*chunk.Game.ChunkHeight)*chunk.Game.CellSize,
-				chunk.Game.CellSize,
-				chunk.Game.CellSize,
-				chunk.Game.ElementData[cell.Type].Color,
-				false,
-			)
-		}
+// Seed derives the random seed the chunk uses for the current tick, so that
+// the outcome of a tick does not depend on the order chunks are updated in.
+func (chunk *Chunk) Seed() int64 {
+	return util.Mix(uint64(chunk.World.Seed), chunk.World.Tick, uint64(chunk.X), uint64(chunk.Y))
+}
+
+// Phase returns which of the checkerboard phases the chunk is updated in.
+// Chunks that share a phase are never adjacent to each other.
+func (chunk *Chunk) Phase() int {
+	return chunk.X&1 + chunk.Y&1*2
+}
+
+// Tick reseeds the chunk's random source for the current tick and updates
+// the cells that were woken up since the last one.
+func (chunk *Chunk) Tick() error {
+	chunk.dirtyLock.Lock()
+	chunk.Dirty, chunk.nextDirty, chunk.laterDirty = chunk.nextDirty, chunk.laterDirty, EmptyRect()
+	chunk.tickedAt = chunk.World.Tick + 1
+	chunk.dirtyLock.Unlock()
+
+	if chunk.Dirty.Empty() {
+		return nil
+	}
+
+	chunk.Rand.Seed(chunk.Seed())
+	return chunk.Update(chunk.Rand)
+}
+
+// Bounds returns the rect covering every cell of the chunk, in local
+// coordinates.
+func (chunk *Chunk) Bounds() Rect {
+	return Rect{0, 0, chunk.World.ChunkWidth - 1, chunk.World.ChunkHeight - 1}
+}
+
+// WorldBounds returns the rect covering every cell of the chunk, in world
+// coordinates.
+func (chunk *Chunk) WorldBounds() Rect {
+	x := chunk.X * chunk.World.ChunkWidth
+	y := chunk.Y * chunk.World.ChunkHeight
+	return Rect{x, y, x + chunk.World.ChunkWidth - 1, y + chunk.World.ChunkHeight - 1}
+}
+
+// Wake schedules the given local rect to be updated on the next tick.
+//
+// While the world is updating, a chunk that has yet to update in the current
+// tick gets woken up by its neighbours for the current tick instead. Cells
+// moving in from them count as updated already and are skipped, so the rect
+// is woken up for the tick after as well, when those cells get their turn.
+func (chunk *Chunk) Wake(rect Rect) {
+	rect = rect.Intersect(chunk.Bounds())
+	if rect.Empty() {
+		return
+	}
+	chunk.dirtyLock.Lock()
+	chunk.nextDirty = chunk.nextDirty.Union(rect)
+	if chunk.World.updating && chunk.tickedAt != chunk.World.Tick+1 {
+		chunk.laterDirty = chunk.laterDirty.Union(rect)
 	}
+	chunk.dirtyLock.Unlock()
+}
+
+// Sleeping reports whether nothing in the chunk is scheduled for an update.
+func (chunk *Chunk) Sleeping() bool {
+	chunk.dirtyLock.Lock()
+	defer chunk.dirtyLock.Unlock()
+	return chunk.nextDirty.Empty()
+}
+
+// NextDirty returns the rect of cells scheduled for the next tick.
+func (chunk *Chunk) NextDirty() Rect {
+	chunk.dirtyLock.Lock()
+	defer chunk.dirtyLock.Unlock()
+	return chunk.nextDirty
 }
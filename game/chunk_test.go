@@ -0,0 +1,141 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFallingAcrossChunks(t *testing.T) {
+	// Chunks update in an order that changes every tick, so try a few seeds
+	// and columns to cross chunk borders in every order.
+	for seed := int64(1); seed <= 4; seed++ {
+		for _, x := range []int{3, 9, 10, 15} {
+			t.Run(fmt.Sprintf("seed %v column %v", seed, x), func(t *testing.T) {
+				world, err := NewWorld(2, 3, 10, 10, seed, "../data")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer world.Close()
+				sand := lookup(t, world, "sand")
+				cell, _ := world.GetCell(x, 1)
+				cell.Place(sand)
+
+				if err := world.Step(60); err != nil {
+					t.Fatal(err)
+				}
+				bottom, _ := world.GetCell(x, world.TotalHeight()-2)
+				if bottom.Type != sand {
+					t.Error("sand stopped falling before reaching the bottom")
+				}
+			})
+		}
+	}
+}
+
+func TestSettledWorldSleeps(t *testing.T) {
+	for seed := int64(1); seed <= 4; seed++ {
+		t.Run(fmt.Sprintf("seed %v", seed), func(t *testing.T) {
+			world, err := NewWorld(2, 2, 10, 10, seed, "../data")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer world.Close()
+			sand := lookup(t, world, "sand")
+			for y := 2; y <= 5; y++ {
+				for x := 4; x <= 15; x++ {
+					cell, _ := world.GetCell(x, y)
+					cell.SetType(sand)
+				}
+			}
+
+			if err := world.Step(100); err != nil {
+				t.Fatal(err)
+			}
+			for _, chunk := range world.Chunks {
+				if !chunk.Sleeping() {
+					t.Errorf("chunk %v %v is still awake with %v", chunk.X, chunk.Y, chunk.NextDirty())
+				}
+			}
+		})
+	}
+}
+
+func TestChangeWakesChunk(t *testing.T) {
+	world := newTestWorld(t, 2, 2, 10, 10)
+	if err := world.Step(3); err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range world.Chunks {
+		if !chunk.Sleeping() {
+			t.Fatalf("chunk %v %v of an empty world is awake", chunk.X, chunk.Y)
+		}
+	}
+
+	// A change on the border of a chunk wakes its neighbours as well.
+	cell, _ := world.GetCell(9, 5)
+	cell.SetType(lookup(t, world, "sand"))
+	for _, test := range []struct {
+		chunk int
+		awake bool
+		dirty Rect
+	}{
+		{0, true, Rect{8, 4, 9, 6}},
+		{1, true, Rect{0, 4, 0, 6}},
+		{2, false, EmptyRect()},
+		{3, false, EmptyRect()},
+	} {
+		chunk := world.Chunks[test.chunk]
+		if chunk.Sleeping() == test.awake || chunk.NextDirty() != test.dirty {
+			t.Errorf("chunk %v %v has %v to update, expected %v", chunk.X, chunk.Y, chunk.NextDirty(), test.dirty)
+		}
+	}
+}
+
+func TestReactionsLetChunksSleep(t *testing.T) {
+	element := func(name, properties, reaction string) string {
+		return `<element name="` + name + `">
+  <immovable-solid />
+  ` + properties + `
+  <reactions>
+    <reaction>
+      ` + reaction + `
+    </reaction>
+  </reactions>
+</element>`
+	}
+	sleeping := func(expected bool) func(t *testing.T, world *World) {
+		return func(t *testing.T, world *World) {
+			if sleeping := world.Chunks[0].Sleeping(); sleeping != expected {
+				t.Errorf("chunk is sleeping: %v, expected %v", sleeping, expected)
+			}
+		}
+	}
+
+	tests := []struct {
+		name       string
+		properties string
+		reaction   string
+		sleeping   bool
+	}{
+		{"chance before an unmet condition", "", "<chance>.5</chance><touching>fire</touching><turn-into>fire</turn-into>", true},
+		{"chance after an unmet condition", "", "<touching>fire</touching><chance>.5</chance><turn-into>fire</turn-into>", true},
+		{"chance still to roll", "", "<chance>.001</chance><turn-into>wood</turn-into>", false},
+		{"age still to come", "", `<age ge="1000" /><turn-into>wood</turn-into>`, false},
+		{"age gone by", "", `<age lt="2" /><chance>.5</chance><turn-into>wood</turn-into>`, true},
+		{"age in a nested condition", "", `<any><age eq="500" /></any><turn-into>wood</turn-into>`, false},
+		{"counting", `<property name="count" />`, `<add-property name="count" />`, false},
+		{"counted to the limit", `<property name="count" default="2147483647" />`, `<add-property name="count" />`, true},
+	}
+
+	var reactionTests []reactionTest
+	for _, test := range tests {
+		reactionTests = append(reactionTests, reactionTest{
+			name:     test.name,
+			elements: element("waiting", test.properties, test.reaction),
+			paint:    []paint{{Rect{3, 3, 6, 6}, "waiting"}},
+			ticks:    10,
+			check:    sleeping(test.sleeping),
+		})
+	}
+	runReactionTests(t, reactionTests)
+}
@@ -0,0 +1,63 @@
+package game
+
+// SPEEDS are the simulation speeds a Clock can run at, in ticks per frame.
+var SPEEDS = []float64{0.25, 0.5, 1, 2, 4, 8}
+
+// DEFAULT_SPEED is the index of 1x in SPEEDS.
+const DEFAULT_SPEED = 2
+
+// Clock decides how many ticks to run each frame. Speeds below 1x skip
+// frames and speeds above run several ticks per frame. While paused it only
+// runs the ticks asked for with Step.
+type Clock struct {
+	Paused bool
+	Speed  int
+
+	pending float64
+	steps   int
+}
+
+func NewClock() *Clock {
+	return &Clock{Speed: DEFAULT_SPEED}
+}
+
+// Ticks returns how many ticks to run for the current frame.
+func (clock *Clock) Ticks() int {
+	if clock.Paused {
+		steps := clock.steps
+		clock.steps = 0
+		return steps
+	}
+	clock.pending += SPEEDS[clock.Speed]
+	ticks := int(clock.pending)
+	clock.pending -= float64(ticks)
+	return ticks
+}
+
+// TogglePause pauses or resumes the clock.
+func (clock *Clock) TogglePause() {
+	clock.Paused = !clock.Paused
+	clock.pending = 0
+	clock.steps = 0
+}
+
+// Step pauses the clock and runs exactly one tick on the next frame.
+func (clock *Clock) Step() {
+	if !clock.Paused {
+		clock.TogglePause()
+	}
+	clock.steps++
+}
+
+func (clock *Clock) Faster() {
+	clock.Speed = min(clock.Speed+1, len(SPEEDS)-1)
+}
+
+func (clock *Clock) Slower() {
+	clock.Speed = max(clock.Speed-1, 0)
+}
+
+// Multiplier returns the current speed in ticks per frame.
+func (clock *Clock) Multiplier() float64 {
+	return SPEEDS[clock.Speed]
+}
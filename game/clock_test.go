@@ -0,0 +1,90 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClockTicks(t *testing.T) {
+	tests := []struct {
+		name     string
+		speed    int
+		expected []int
+	}{
+		// The remainder of each frame carries over to the next one.
+		{"0.25x", 0, []int{0, 0, 0, 1, 0, 0, 0, 1}},
+		{"0.5x", 1, []int{0, 1, 0, 1}},
+		{"1x", DEFAULT_SPEED, []int{1, 1, 1}},
+		{"8x", len(SPEEDS) - 1, []int{8, 8, 8}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := NewClock()
+			clock.Speed = test.speed
+			ticks := make([]int, len(test.expected))
+			for i := range ticks {
+				ticks[i] = clock.Ticks()
+			}
+			if !slices.Equal(ticks, test.expected) {
+				t.Errorf("got ticks %v, expected %v", ticks, test.expected)
+			}
+		})
+	}
+}
+
+func TestClockStep(t *testing.T) {
+	clock := NewClock()
+	clock.Step()
+	if !clock.Paused {
+		t.Fatal("stepping didn't pause the clock")
+	}
+	clock.Step()
+
+	ticks := []int{clock.Ticks(), clock.Ticks()}
+	if !slices.Equal(ticks, []int{2, 0}) {
+		t.Errorf("got ticks %v after two steps, expected [2 0]", ticks)
+	}
+}
+
+func TestClockTogglePause(t *testing.T) {
+	clock := NewClock()
+	clock.Speed = 0
+	clock.Ticks()
+	clock.Ticks()
+
+	clock.TogglePause()
+	if !clock.Paused {
+		t.Fatal("the clock didn't pause")
+	}
+	if ticks := clock.Ticks(); ticks != 0 {
+		t.Errorf("got %v ticks while paused, expected 0", ticks)
+	}
+
+	// Steps and the remainder of a frame don't survive pausing.
+	clock.Step()
+	clock.TogglePause()
+	if clock.Paused {
+		t.Fatal("the clock didn't resume")
+	}
+	ticks := []int{clock.Ticks(), clock.Ticks(), clock.Ticks(), clock.Ticks()}
+	if !slices.Equal(ticks, []int{0, 0, 0, 1}) {
+		t.Errorf("got ticks %v after resuming, expected [0 0 0 1]", ticks)
+	}
+}
+
+func TestClockSpeed(t *testing.T) {
+	clock := NewClock()
+	for range len(SPEEDS) {
+		clock.Faster()
+	}
+	if multiplier := clock.Multiplier(); multiplier != 8 {
+		t.Errorf("fastest speed is %vx, expected 8x", multiplier)
+	}
+	for range len(SPEEDS) {
+		clock.Slower()
+	}
+	if multiplier := clock.Multiplier(); multiplier != 0.25 {
+		t.Errorf("slowest speed is %vx, expected 0.25x", multiplier)
+	}
+}
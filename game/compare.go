@@ -0,0 +1,81 @@
+package game
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Comparison checks a number against a value, as written in reaction
+// attributes like lt="1" or ge="100".
+type Comparison struct {
+	Operator string
+	Value    float64
+}
+
+var comparisonOperators = []string{"lt", "le", "gt", "ge", "eq", "ne"}
+
+// ParseComparisons reads every comparison out of the attributes of a
+// reaction step. Attributes listed in other are skipped, anything else is an
+// error.
+func ParseComparisons(attrs []xml.Attr, other ...string) ([]Comparison, error) {
+	comparisons := make([]Comparison, 0, len(attrs))
+	for _, attr := range attrs {
+		if slices.Contains(other, attr.Name.Local) {
+			continue
+		}
+		if !slices.Contains(comparisonOperators, attr.Name.Local) {
+			return nil, fmt.Errorf("unknown attribute '%v'", attr.Name.Local)
+		}
+		value, err := strconv.ParseFloat(attr.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("error while parsing float in xml: %v", err)
+		}
+		comparisons = append(comparisons, Comparison{attr.Name.Local, value})
+	}
+	if len(comparisons) == 0 {
+		return nil, errors.New("expected at least one of lt, le, gt, ge, eq or ne")
+	}
+	return comparisons, nil
+}
+
+func (comparison Comparison) Holds(value float64) bool {
+	switch comparison.Operator {
+	case "lt":
+		return value < comparison.Value
+	case "le":
+		return value <= comparison.Value
+	case "gt":
+		return value > comparison.Value
+	case "ge":
+		return value >= comparison.Value
+	case "eq":
+		return value == comparison.Value
+	case "ne":
+		return value != comparison.Value
+	}
+	return false
+}
+
+// CompareAll reports whether value passes every comparison.
+func CompareAll(value float64, comparisons []Comparison) bool {
+	for _, comparison := range comparisons {
+		if !comparison.Holds(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// AttrValue returns the value of the named attribute of a reaction step, or
+// an empty string if it has none.
+func AttrValue(attrs []xml.Attr, name string) string {
+	for _, attr := range attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -1,7 +1,9 @@
 package game
 
 import (
+	"errors"
 	"go-falling-sand/util"
+	"math"
 	"math/rand"
 )
 
@@ -9,16 +11,46 @@ const CUSTOM_DO_NOTHING = 0
 const CUSTOM_END = 1
 
 type Condition interface {
-	Satisfied(cell *Cell) (bool, error)
+	Satisfied(cell *Cell, rng *rand.Rand) (bool, error)
 }
 
 type Action interface {
-	Act(cell *Cell) (int, error)
+	Act(cell *Cell, rng *rand.Rand) (int, error)
 }
 
+// PendingCondition is a condition that can come true on a later tick even if
+// nothing around the cell changes, like a chance roll or the age of the cell.
+// Pending reports whether it still can after failing on this one.
+type PendingCondition interface {
+	Condition
+	Pending(cell *Cell) bool
+}
+
+// TargetCondition is a condition that is satisfied by neighbours, and can
+// pick one of the neighbours satisfying it for the actions of its reaction
+// to work on. Target returns nil if no neighbour does.
+type TargetCondition interface {
+	Condition
+	Target(cell *Cell, rng *rand.Rand) (*Cell, error)
+}
+
+// TargetAction is an action that works on the neighbour picked by the
+// reaction's TargetCondition rather than on the reacting cell.
+type TargetAction interface {
+	Action
+	ActOn(cell, target *Cell, rng *rand.Rand) (int, error)
+}
+
+var errNoTarget = errors.New("action needs a neighbour picked by a condition like <touching>")
+
 type Reaction struct {
 	Conditions []Condition
 	Actions    []Action
+	// Target is the index of the condition picking the neighbour for the
+	// TargetActions of the reaction, or -1 if it has none. Only that
+	// condition rolls for a target, so reactions without target actions
+	// don't spend random numbers on it.
+	Target int
 }
 
 func (*Reaction) IsA(kind string) bool {
@@ -29,21 +61,40 @@ func (kind *Reaction) Create(cell *Cell) error {
 	return nil
 }
 
-func (kind *Reaction) Update(cell *Cell) error {
+func (kind *Reaction) Update(cell *Cell, rng *rand.Rand) error {
+	var target *Cell
 	for i := range kind.Conditions {
 		condition := kind.Conditions[i]
-		res, err := condition.Satisfied(cell)
+		if i == kind.Target {
+			other, err := condition.(TargetCondition).Target(cell, rng)
+			if err != nil {
+				return err
+			}
+			if other == nil {
+				return nil
+			}
+			target = other
+			continue
+		}
+		res, err := condition.Satisfied(cell, rng)
 		if err != nil {
 			return err
 		}
 		if !res {
-			return nil
+			return kind.wait(cell, i, rng)
 		}
 	}
 
 	for i := range kind.Actions {
 		result := kind.Actions[i]
-		if res, err := result.Act(cell); err != nil {
+		var res int
+		var err error
+		if targeted, ok := result.(TargetAction); ok {
+			res, err = targeted.ActOn(cell, target, rng)
+		} else {
+			res, err = result.Act(cell, rng)
+		}
+		if err != nil {
 			return err
 		} else if res == CUSTOM_END {
 			return nil
@@ -53,12 +104,49 @@ func (kind *Reaction) Update(cell *Cell) error {
 	return nil
 }
 
+// wait keeps the cell awake after the condition at index failed, if that
+// condition may still pass on a later tick and the conditions after it hold
+// now. Otherwise nothing the reaction looks at can change without waking the
+// cell anyway, and its chunk is free to fall asleep.
+func (kind *Reaction) wait(cell *Cell, index int, rng *rand.Rand) error {
+	if pending, ok := kind.Conditions[index].(PendingCondition); !ok || !pending.Pending(cell) {
+		return nil
+	}
+	for _, condition := range kind.Conditions[index+1:] {
+		if pending, ok := condition.(PendingCondition); ok && pending.Pending(cell) {
+			continue
+		}
+		if res, err := condition.Satisfied(cell, rng); err != nil || !res {
+			return err
+		}
+	}
+	cell.KeepAwake()
+	return nil
+}
+
+// pickNeighbour returns a random one of the neighbours at the given offsets
+// that match, or nil if none does.
+func pickNeighbour(cell *Cell, offsets [][2]int, match func(other *Cell) bool, rng *rand.Rand) *Cell {
+	var candidates [8]*Cell
+	count := 0
+	for _, offset := range offsets {
+		if other, err := cell.GetCell(offset[0], offset[1]); err == nil && match(other) {
+			candidates[count] = other
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	return candidates[rng.Intn(count)]
+}
+
 type TurnInto struct {
 	ID int
 }
 
-func (kind *TurnInto) Act(cell *Cell) (int, error) {
-	cell.Type = kind.ID
+func (kind *TurnInto) Act(cell *Cell, rng *rand.Rand) (int, error) {
+	cell.SetType(kind.ID)
 	return CUSTOM_DO_NOTHING, nil
 }
 
@@ -66,21 +154,28 @@ type Chance struct {
 	Chance float32
 }
 
-func (kind *Chance) Satisfied(cell *Cell) (bool, error) {
-	return rand.Float32() < kind.Chance, nil
+func (kind *Chance) Satisfied(cell *Cell, rng *rand.Rand) (bool, error) {
+	return rng.Float32() < kind.Chance, nil
 }
 
+// Pending reports whether a later roll can succeed.
+func (kind *Chance) Pending(cell *Cell) bool {
+	return kind.Chance > 0
+}
+
+// Touching is satisfied if any of the eight surrounding cells is one of
+// Elements.
 type Touching struct {
-	ID int
+	Elements ElementSet
 }
 
-func (kind *Touching) Satisfied(cell *Cell) (bool, error) {
+func (kind *Touching) Satisfied(cell *Cell, rng *rand.Rand) (bool, error) {
 	for x := -1; x <= 1; x++ {
 		for y := -1; y <= 1; y++ {
 			if !(x == 0 && y == 0) {
 				if other, err := cell.GetCell(x, y); err != nil {
 					continue
-				} else if other.Type == kind.ID {
+				} else if kind.Elements.Has(other.Type) {
 					return true, nil
 				}
 			}
@@ -89,17 +184,25 @@ func (kind *Touching) Satisfied(cell *Cell) (bool, error) {
 	return false, nil
 }
 
+func (kind *Touching) Target(cell *Cell, rng *rand.Rand) (*Cell, error) {
+	return pickNeighbour(cell, MOORE_NEIGHBOURHOOD, func(other *Cell) bool {
+		return kind.Elements.Has(other.Type)
+	}, rng), nil
+}
+
+// DirectlyTouching is Touching for the four cells sharing a side with the
+// cell.
 type DirectlyTouching struct {
-	ID int
+	Elements ElementSet
 }
 
-func (kind *DirectlyTouching) Satisfied(cell *Cell) (bool, error) {
+func (kind *DirectlyTouching) Satisfied(cell *Cell, rng *rand.Rand) (bool, error) {
 	for x := -1; x <= 1; x++ {
 		for y := -1; y <= 1; y++ {
 			if x == 0 || y == 0 {
 				if other, err := cell.GetCell(x, y); err != nil {
 					continue
-				} else if other.Type == kind.ID {
+				} else if kind.Elements.Has(other.Type) {
 					return true, nil
 				}
 			}
@@ -108,26 +211,287 @@ func (kind *DirectlyTouching) Satisfied(cell *Cell) (bool, error) {
 	return false, nil
 }
 
+func (kind *DirectlyTouching) Target(cell *Cell, rng *rand.Rand) (*Cell, error) {
+	return pickNeighbour(cell, VON_NEUMANN_NEIGHBOURHOOD, func(other *Cell) bool {
+		return kind.Elements.Has(other.Type)
+	}, rng), nil
+}
+
+// DIRECTIONS maps the names of the directional conditions to the offset of
+// the cell they look at.
+var DIRECTIONS = map[string][2]int{
+	"above":    {0, -1},
+	"below":    {0, 1},
+	"left-of":  {-1, 0},
+	"right-of": {1, 0},
+}
+
+// Adjacent is satisfied if the cell at DX DY from the reacting cell is one
+// of Elements.
+type Adjacent struct {
+	DX, DY   int
+	Elements ElementSet
+}
+
+func (kind *Adjacent) Satisfied(cell *Cell, rng *rand.Rand) (bool, error) {
+	other, err := cell.GetCell(kind.DX, kind.DY)
+	return err == nil && kind.Elements.Has(other.Type), nil
+}
+
+func (kind *Adjacent) Target(cell *Cell, rng *rand.Rand) (*Cell, error) {
+	other, err := cell.GetCell(kind.DX, kind.DY)
+	if err != nil || !kind.Elements.Has(other.Type) {
+		return nil, nil
+	}
+	return other, nil
+}
+
+var (
+	MOORE_NEIGHBOURHOOD = [][2]int{
+		{-1, -1}, {0, -1}, {1, -1},
+		{-1, 0}, {1, 0},
+		{-1, 1}, {0, 1}, {1, 1},
+	}
+	VON_NEUMANN_NEIGHBOURHOOD = [][2]int{{0, -1}, {-1, 0}, {1, 0}, {0, 1}}
+)
+
+// NEIGHBOURHOODS maps the names used in xml to neighbourhoods.
+var NEIGHBOURHOODS = map[string][][2]int{
+	"moore":       MOORE_NEIGHBOURHOOD,
+	"von-neumann": VON_NEUMANN_NEIGHBOURHOOD,
+}
+
+// Neighbours is satisfied if between Min and Max cells of the neighbourhood,
+// both included, are one of Elements.
+type Neighbours struct {
+	Elements      ElementSet
+	Min, Max      int
+	Neighbourhood [][2]int
+}
+
+func (kind *Neighbours) Satisfied(cell *Cell, rng *rand.Rand) (bool, error) {
+	count := 0
+	for _, offset := range kind.Neighbourhood {
+		if other, err := cell.GetCell(offset[0], offset[1]); err == nil && kind.Elements.Has(other.Type) {
+			count++
+			if count > kind.Max {
+				return false, nil
+			}
+		}
+	}
+	return count >= kind.Min, nil
+}
+
+type Temperature struct {
+	Comparisons []Comparison
+}
+
+func (kind *Temperature) Satisfied(cell *Cell, rng *rand.Rand) (bool, error) {
+	return CompareAll(float64(cell.Temperature), kind.Comparisons), nil
+}
+
+// PropertyCondition compares a property of cells of Element.
+type PropertyCondition struct {
+	Element     int
+	Slot        int
+	Comparisons []Comparison
+}
+
+func (kind *PropertyCondition) Satisfied(cell *Cell, rng *rand.Rand) (bool, error) {
+	if cell.Type != kind.Element {
+		return false, nil
+	}
+	return CompareAll(float64(cell.Data()[kind.Slot]), kind.Comparisons), nil
+}
+
+// SetProperty sets a property of cells of Element.
+type SetProperty struct {
+	Element int
+	Slot    int
+	Value   int32
+}
+
+func (kind *SetProperty) Act(cell *Cell, rng *rand.Rand) (int, error) {
+	if cell.Type == kind.Element {
+		data := cell.Data()
+		if data[kind.Slot] != kind.Value {
+			data[kind.Slot] = kind.Value
+			cell.KeepAwake()
+		}
+	}
+	return CUSTOM_DO_NOTHING, nil
+}
+
+// AddProperty adds Amount to a property of cells of Element, which may be
+// negative to subtract from it. The result sticks to the range of an int32
+// instead of overflowing.
+type AddProperty struct {
+	Element int
+	Slot    int
+	Amount  int32
+}
+
+func (kind *AddProperty) Act(cell *Cell, rng *rand.Rand) (int, error) {
+	if cell.Type == kind.Element {
+		data := cell.Data()
+		value := int32(min(max(int64(data[kind.Slot])+int64(kind.Amount), math.MinInt32), math.MaxInt32))
+		if data[kind.Slot] != value {
+			data[kind.Slot] = value
+			cell.KeepAwake()
+		}
+	}
+	return CUSTOM_DO_NOTHING, nil
+}
+
+// Age compares how many ticks ago the cell turned into its element.
+type Age struct {
+	Comparisons []Comparison
+}
+
+func (kind *Age) Satisfied(cell *Cell, rng *rand.Rand) (bool, error) {
+	return CompareAll(float64(cell.Age()), kind.Comparisons), nil
+}
+
+// Pending reports whether the cell passes the comparisons at some later
+// age. The bounds they set leave a range of ages, and as every ne rules out
+// only one of them, the first few ages of the range are enough to try.
+func (kind *Age) Pending(cell *Cell) bool {
+	low, high := float64(cell.Age())+1, math.Inf(1)
+	for _, comparison := range kind.Comparisons {
+		switch comparison.Operator {
+		case "lt":
+			high = min(high, math.Ceil(comparison.Value)-1)
+		case "le":
+			high = min(high, math.Floor(comparison.Value))
+		case "gt":
+			low = max(low, math.Floor(comparison.Value)+1)
+		case "ge":
+			low = max(low, math.Ceil(comparison.Value))
+		case "eq":
+			low, high = max(low, math.Ceil(comparison.Value)), min(high, math.Floor(comparison.Value))
+		}
+	}
+	for age := low; age <= high && age <= low+float64(len(kind.Comparisons)); age++ {
+		if CompareAll(age, kind.Comparisons) {
+			return true
+		}
+	}
+	return false
+}
+
+type Heat struct {
+	Amount float32
+}
+
+func (kind *Heat) Act(cell *Cell, rng *rand.Rand) (int, error) {
+	cell.AddHeat(kind.Amount)
+	return CUSTOM_DO_NOTHING, nil
+}
+
+// EMIT_DIRECTIONS maps the names of the directions <emit> can be aimed in
+// to offsets.
+var EMIT_DIRECTIONS = map[string][2]int{
+	"up":         {0, -1},
+	"down":       {0, 1},
+	"left":       {-1, 0},
+	"right":      {1, 0},
+	"up-left":    {-1, -1},
+	"up-right":   {1, -1},
+	"down-left":  {-1, 1},
+	"down-right": {1, 1},
+}
+
+// Emit places ID into an adjacent air cell, DX DY away if Directed and in a
+// random direction otherwise.
 type Emit struct {
-	ID int
+	ID       int
+	Directed bool
+	DX, DY   int
 }
 
-func (kind *Emit) Act(cell *Cell) (int, error) {
-	dx, dy := util.GetRandomDir()
+func (kind *Emit) Act(cell *Cell, rng *rand.Rand) (int, error) {
+	dx, dy := kind.DX, kind.DY
+	if !kind.Directed {
+		dx, dy = util.GetRandomDir(rng)
+	}
 	other, err := cell.GetCell(dx, dy)
 	if err != nil {
 		return CUSTOM_DO_NOTHING, nil
 	}
 	if other.ElementData().Role != ROLE_AIR {
+		cell.KeepAwake()
 		return CUSTOM_DO_NOTHING, nil
 	}
-	other.Type = kind.ID
+	other.Place(kind.ID)
+	return CUSTOM_DO_NOTHING, nil
+}
+
+// ConvertNeighbour turns the target into ID, which keeps its temperature
+// like TurnInto does.
+type ConvertNeighbour struct {
+	ID int
+}
+
+func (kind *ConvertNeighbour) Act(cell *Cell, rng *rand.Rand) (int, error) {
+	return CUSTOM_END, errNoTarget
+}
+
+func (kind *ConvertNeighbour) ActOn(cell, target *Cell, rng *rand.Rand) (int, error) {
+	target.SetType(kind.ID)
 	return CUSTOM_DO_NOTHING, nil
 }
 
+// ConsumeNeighbour replaces the target with air.
+type ConsumeNeighbour struct{}
+
+func (kind *ConsumeNeighbour) Act(cell *Cell, rng *rand.Rand) (int, error) {
+	return CUSTOM_END, errNoTarget
+}
+
+func (kind *ConsumeNeighbour) ActOn(cell, target *Cell, rng *rand.Rand) (int, error) {
+	target.Place(cell.World().AirElement)
+	return CUSTOM_DO_NOTHING, nil
+}
+
+// SwapWith swaps the cell with a random one of its neighbours that is one of
+// Elements. The rest of the reaction is skipped after a swap, as the
+// reacting cell has moved on.
+type SwapWith struct {
+	Elements ElementSet
+}
+
+func (kind *SwapWith) Act(cell *Cell, rng *rand.Rand) (int, error) {
+	other := pickNeighbour(cell, MOORE_NEIGHBOURHOOD, func(other *Cell) bool {
+		return kind.Elements.Has(other.Type)
+	}, rng)
+	if other == nil {
+		return CUSTOM_DO_NOTHING, nil
+	}
+	if other.HasUpdated() {
+		// Like with moving, try again on the next tick.
+		cell.KeepAwake()
+		return CUSTOM_DO_NOTHING, nil
+	}
+	if _, err := cell.Switch(other, rng); err != nil {
+		return CUSTOM_END, err
+	}
+	return CUSTOM_END, nil
+}
+
+// anyPending reports whether any of the conditions is still pending, which
+// is what the conditions combining others go by.
+func anyPending(cell *Cell, conditions []Condition) bool {
+	for _, condition := range conditions {
+		if pending, ok := condition.(PendingCondition); ok && pending.Pending(cell) {
+			return true
+		}
+	}
+	return false
+}
+
 type End struct{}
 
-func (End) Act(cell *Cell) (int, error) {
+func (End) Act(cell *Cell, rng *rand.Rand) (int, error) {
 	return CUSTOM_END, nil
 }
 
@@ -135,9 +499,9 @@ type Any struct {
 	Conditions []Condition
 }
 
-func (any *Any) Satisfied(cell *Cell) (bool, error) {
+func (any *Any) Satisfied(cell *Cell, rng *rand.Rand) (bool, error) {
 	for _, condition := range any.Conditions {
-		if res, err := condition.Satisfied(cell); err != nil {
+		if res, err := condition.Satisfied(cell, rng); err != nil {
 			return false, err
 		} else if res {
 			return true, nil
@@ -146,13 +510,17 @@ func (any *Any) Satisfied(cell *Cell) (bool, error) {
 	return false, nil
 }
 
+func (any *Any) Pending(cell *Cell) bool {
+	return anyPending(cell, any.Conditions)
+}
+
 type None struct {
 	Consitions []Condition
 }
 
-func (none *None) Satisfied(cell *Cell) (bool, error) {
+func (none *None) Satisfied(cell *Cell, rng *rand.Rand) (bool, error) {
 	for _, condition := range none.Consitions {
-		if res, err := condition.Satisfied(cell); err != nil {
+		if res, err := condition.Satisfied(cell, rng); err != nil {
 			return false, err
 		} else if res {
 			return false, nil
@@ -161,13 +529,17 @@ func (none *None) Satisfied(cell *Cell) (bool, error) {
 	return true, nil
 }
 
+func (none *None) Pending(cell *Cell) bool {
+	return anyPending(cell, none.Consitions)
+}
+
 type All struct {
 	Conditions []Condition
 }
 
-func (all *All) Satisfied(cell *Cell) (bool, error) {
+func (all *All) Satisfied(cell *Cell, rng *rand.Rand) (bool, error) {
 	for _, condition := range all.Conditions {
-		if res, err := condition.Satisfied(cell); err != nil {
+		if res, err := condition.Satisfied(cell, rng); err != nil {
 			return false, err
 		} else if !res {
 			return false, nil
@@ -176,13 +548,17 @@ func (all *All) Satisfied(cell *Cell) (bool, error) {
 	return true, nil
 }
 
+func (all *All) Pending(cell *Cell) bool {
+	return anyPending(cell, all.Conditions)
+}
+
 type Not struct {
 	Conditions []Condition
 }
 
-func (not *Not) Satisfied(cell *Cell) (bool, error) {
+func (not *Not) Satisfied(cell *Cell, rng *rand.Rand) (bool, error) {
 	for _, condition := range not.Conditions {
-		if res, err := condition.Satisfied(cell); err != nil {
+		if res, err := condition.Satisfied(cell, rng); err != nil {
 			return false, err
 		} else if !res {
 			return true, nil
@@ -190,3 +566,7 @@ func (not *Not) Satisfied(cell *Cell) (bool, error) {
 	}
 	return false, nil
 }
+
+func (not *Not) Pending(cell *Cell) bool {
+	return anyPending(cell, not.Conditions)
+}
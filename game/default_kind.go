@@ -1,12 +1,14 @@
 package game
 
+import "math/rand"
+
 type DefaultKind struct{}
 
 func (DefaultKind) Create(cell *Cell) error {
 	return nil
 }
 
-func (DefaultKind) Update(cell *Cell) error {
+func (DefaultKind) Update(cell *Cell, rng *rand.Rand) error {
 	return nil
 }
 
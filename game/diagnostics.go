@@ -0,0 +1,74 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Diagnostic is a problem found while loading element definitions, pointing
+// at where it was found when that is known.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (diagnostic Diagnostic) Error() string {
+	switch {
+	case diagnostic.File != "" && diagnostic.Line > 0:
+		return fmt.Sprintf("%v:%v: %v", diagnostic.File, diagnostic.Line, diagnostic.Message)
+	case diagnostic.File != "":
+		return fmt.Sprintf("%v: %v", diagnostic.File, diagnostic.Message)
+	}
+	return diagnostic.Message
+}
+
+// Diagnostics collects every problem found instead of stopping at the first
+// one.
+type Diagnostics []Diagnostic
+
+func (diagnostics Diagnostics) Error() string {
+	messages := make([]string, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		messages[i] = diagnostic.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Add reports a problem found at the given line.
+func (diagnostics *Diagnostics) Add(line int, format string, args ...any) {
+	*diagnostics = append(*diagnostics, Diagnostic{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// Merge adds every diagnostic an error carries, or the error itself at the
+// given line if it isn't made of diagnostics.
+func (diagnostics *Diagnostics) Merge(line int, err error) {
+	if err == nil {
+		return
+	}
+	var other Diagnostics
+	if errors.As(err, &other) {
+		*diagnostics = append(*diagnostics, other...)
+		return
+	}
+	diagnostics.Add(line, "%v", err)
+}
+
+// InFile sets the file of every diagnostic that doesn't have one yet.
+func (diagnostics Diagnostics) InFile(file string) Diagnostics {
+	for i := range diagnostics {
+		if diagnostics[i].File == "" {
+			diagnostics[i].File = file
+		}
+	}
+	return diagnostics
+}
+
+// Err returns the diagnostics as an error, or nil if there are none.
+func (diagnostics Diagnostics) Err() error {
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	return diagnostics
+}
@@ -1,5 +1,7 @@
 package game
 
+import "math/rand"
+
 type Dust struct {
 	Weight float32
 }
@@ -12,16 +14,16 @@ func (Dust) Create(cell *Cell) error {
 	return nil
 }
 
-func (dust *Dust) Update(cell *Cell) error {
+func (dust *Dust) Update(cell *Cell, rng *rand.Rand) error {
 	bottom, err := cell.GetCell(0, 1)
 	if err == nil && !(cell.CanFallInto(bottom) && !bottom.IsSolid()) {
-		return MovableSolid{}.Update(cell)
+		return MovableSolid{}.Update(cell, rng)
 	} else {
 		top, err := cell.GetCell(0, -1)
 		if err == nil && !(cell.CanFallInto(top) && !top.IsSolid()) {
-			return MovableSolid{}.Update(cell)
+			return MovableSolid{}.Update(cell, rng)
 		}
 	}
 
-	return (&Gas{dust.Weight}).Update(cell)
+	return (&Gas{dust.Weight}).Update(cell, rng)
 }
@@ -17,23 +17,34 @@ func (Gas) Create(cell *Cell) error {
 	return nil
 }
 
-func (gas *Gas) Update(cell *Cell) error {
+func (gas *Gas) Update(cell *Cell, rng *rand.Rand) error {
 	var dx int
 	var dy int
-	if rand.Float32() > gas.Weight {
-		dx, dy = util.GetRandomDir()
+	if rng.Float32() > gas.Weight {
+		dx, dy = util.GetRandomDir(rng)
 	} else {
-		dx = rand.Intn(3) - 1
+		dx = rng.Intn(3) - 1
 		dy = 1
 	}
 
 	other, err := cell.GetCell(dx, dy)
 	if err == nil {
 		if other.IsA("Gas") {
-			cell.Switch(other)
-			return nil
+			moved := other.Type != cell.Type
+			cell.Switch(other, rng)
+			if moved {
+				return nil
+			}
 		}
 	}
 
+	// Gases wander at random, so as long as there is a different gas around
+	// to trade places with, the cell might still move on a later tick.
+	if cell.HasNeighbour(func(other *Cell) bool {
+		return other.IsA("Gas") && other.Type != cell.Type
+	}) {
+		cell.KeepAwake()
+	}
+
 	return nil
 }
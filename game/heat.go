@@ -0,0 +1,197 @@
+package game
+
+import (
+	"image/color"
+	"math"
+
+	"go-falling-sand/xml_handler"
+)
+
+// AMBIENT_TEMPERATURE is the temperature, in degrees, of everything that
+// isn't told otherwise.
+const AMBIENT_TEMPERATURE = 20
+
+const DEFAULT_CONDUCTIVITY = 0.2
+const DEFAULT_HEAT_CAPACITY = 1
+
+// CONDUCTION_RATE scales how much heat crosses between two neighbours per
+// tick. Every pair of awake cells exchanges heat twice, once from each side,
+// so it has to stay well below 1/8 for the temperatures not to overshoot.
+const CONDUCTION_RATE = 0.1
+
+// COOLING_RATE is how much heat a cell loses every tick for every degree it
+// is away from the ambient temperature, so that heat doesn't pile up in the
+// world forever. Elements that take more heat to warm up take longer to cool
+// down.
+const COOLING_RATE = 0.002
+
+// HEAT_THRESHOLD is the smallest change in temperature that keeps a cell and
+// its neighbours awake.
+const HEAT_THRESHOLD = 0.01
+
+// Conduct exchanges heat between the cell and its four direct neighbours.
+func (cell *Cell) Conduct() {
+	data := cell.ElementData()
+	if data.Conductivity == 0 {
+		return
+	}
+
+	for n := range 4 {
+		dx, dy := adjacentDirections[n][0], adjacentDirections[n][1]
+		other, err := cell.GetCell(dx, dy)
+		if err != nil {
+			continue
+		}
+
+		k := min(data.Conductivity, other.ElementData().Conductivity) * CONDUCTION_RATE
+		if k == 0 {
+			continue
+		}
+
+		flow := k * (other.Temperature - cell.Temperature)
+		cell.AddHeat(flow)
+		other.AddHeat(-flow)
+	}
+
+	cell.AddHeat((AMBIENT_TEMPERATURE - cell.Temperature) * COOLING_RATE)
+}
+
+var adjacentDirections = [4][2]int{{0, 1}, {1, 0}, {0, -1}, {-1, 0}}
+
+// AddHeat changes the temperature of the cell by the given amount of heat,
+// divided by the heat capacity of its element.
+func (cell *Cell) AddHeat(heat float32) {
+	change := heat / cell.ElementData().HeatCapacity
+	cell.Temperature += change
+	if math.Abs(float64(change)) > HEAT_THRESHOLD {
+		cell.MarkChanged()
+	}
+}
+
+// SetTemperature sets the temperature of the cell, waking its surroundings.
+func (cell *Cell) SetTemperature(temperature float32) {
+	if math.Abs(float64(temperature-cell.Temperature)) > HEAT_THRESHOLD {
+		cell.MarkChanged()
+	}
+	cell.Temperature = temperature
+}
+
+// heatGradient goes from cold to hot; HeatColor blends between its stops.
+var heatGradient = []struct {
+	Temperature float32
+	Color       color.RGBA
+}{
+	{-50, color.RGBA{0, 0, 120, 255}},
+	{0, color.RGBA{0, 120, 255, 255}},
+	{AMBIENT_TEMPERATURE, color.RGBA{20, 20, 20, 255}},
+	{100, color.RGBA{200, 0, 0, 255}},
+	{400, color.RGBA{255, 160, 0, 255}},
+	{1000, color.RGBA{255, 255, 200, 255}},
+}
+
+// HeatColor returns the colour the heat overlay shows a temperature in.
+func HeatColor(temperature float32) color.RGBA {
+	if temperature <= heatGradient[0].Temperature {
+		return heatGradient[0].Color
+	}
+	for i := 1; i < len(heatGradient); i++ {
+		high := heatGradient[i]
+		if temperature > high.Temperature {
+			continue
+		}
+		low := heatGradient[i-1]
+		t := (temperature - low.Temperature) / (high.Temperature - low.Temperature)
+		return color.RGBA{
+			lerp(low.Color.R, high.Color.R, t),
+			lerp(low.Color.G, high.Color.G, t),
+			lerp(low.Color.B, high.Color.B, t),
+			255,
+		}
+	}
+	return heatGradient[len(heatGradient)-1].Color
+}
+
+func lerp(a, b uint8, t float32) uint8 {
+	return uint8(float32(a) + (float32(b)-float32(a))*t)
+}
+
+// FillHeatPixels works like FillPixels, but colours every cell by its
+// temperature.
+func (chunk *Chunk) FillHeatPixels(pix []byte) {
+	world := chunk.World
+	for y := range world.ChunkHeight {
+		for x := range world.ChunkWidth {
+			i := world.CalculateCellIndex(x, y)
+			col := HeatColor(chunk.Cells[i].Temperature)
+			p := pix[i*4 : i*4+4 : i*4+4]
+			p[0] = col.R
+			p[1] = col.G
+			p[2] = col.B
+			p[3] = col.A
+		}
+	}
+}
+
+// PhaseTransition turns a cell into another element once its temperature
+// reaches a threshold, from below if Rising is set and from above otherwise.
+type PhaseTransition struct {
+	Rising      bool
+	Temperature float32
+	Into        int
+}
+
+func (transition *PhaseTransition) Triggered(temperature float32) bool {
+	if transition.Rising {
+		return temperature >= transition.Temperature
+	}
+	return temperature <= transition.Temperature
+}
+
+// Transition applies the first phase transition of the cell's element that
+// its temperature triggers, and reports whether there was one.
+func (cell *Cell) Transition() bool {
+	for i := range cell.ElementData().Transitions {
+		transition := &cell.ElementData().Transitions[i]
+		if transition.Triggered(cell.Temperature) {
+			cell.SetType(transition.Into)
+			return true
+		}
+	}
+	return false
+}
+
+// DefinePhaseTransitions compiles the <melts-at>, <boils-at>, <ignites-at>,
+// <freezes-at> and <condenses-at> tags of an element.
+func (w *World) DefinePhaseTransitions(definition *xmlhandler.XMLElementDefinition) error {
+	elementData := w.ElementData[w.ElementTypes[definition.Name]]
+
+	groups := []struct {
+		Tag         string
+		Rising      bool
+		Transitions []xmlhandler.XMLTransition
+	}{
+		{"melts-at", true, definition.MeltsAt},
+		{"boils-at", true, definition.BoilsAt},
+		{"ignites-at", true, definition.IgnitesAt},
+		{"freezes-at", false, definition.FreezesAt},
+		{"condenses-at", false, definition.CondensesAt},
+	}
+
+	var diagnostics Diagnostics
+	for _, group := range groups {
+		for _, transition := range group.Transitions {
+			id, ok := w.LookupElement(elementData.Pack, transition.Into)
+			if !ok {
+				diagnostics.Add(transition.Line, "error in <%v>: there is no element named '%v'", group.Tag, transition.Into)
+				continue
+			}
+			elementData.Transitions = append(elementData.Transitions, PhaseTransition{
+				Rising:      group.Rising,
+				Temperature: transition.Temp,
+				Into:        id,
+			})
+		}
+	}
+
+	return diagnostics.Err()
+}
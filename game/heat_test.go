@@ -0,0 +1,217 @@
+package game
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// heatElements conduct heat in every way the tests need.
+var heatElements = map[string]string{
+	"elements/copper.xml": `<element name="copper">
+  <immovable-solid />
+  <material>
+    <conductivity>1</conductivity>
+  </material>
+</element>`,
+	"elements/stone.xml": `<element name="stone">
+  <immovable-solid />
+  <material>
+    <conductivity>1</conductivity>
+    <heat-capacity>4</heat-capacity>
+  </material>
+</element>`,
+	"elements/felt.xml": `<element name="felt">
+  <immovable-solid />
+  <material>
+    <conductivity>0</conductivity>
+  </material>
+</element>`,
+}
+
+func TestConduct(t *testing.T) {
+	tests := []struct {
+		name string
+		// elements and temperatures are those of the conducting cell and
+		// of its neighbour on the right. Every other neighbour is felt,
+		// which doesn't conduct at all.
+		elements     [2]string
+		temperatures [2]float32
+		expected     [2]float32
+	}{
+		// 8 heat flows over, and then the conducting cell cools down by
+		// COOLING_RATE heat for every degree it is off the ambient
+		// temperature.
+		{"hot to cold", [2]string{"copper", "copper"}, [2]float32{100, 20}, [2]float32{92 - 72*COOLING_RATE, 28}},
+		{"cold to hot", [2]string{"copper", "copper"}, [2]float32{20, 100}, [2]float32{28 - 8*COOLING_RATE, 92}},
+		{"into more capacity", [2]string{"copper", "stone"}, [2]float32{100, 20}, [2]float32{92 - 72*COOLING_RATE, 22}},
+		{"out of more capacity", [2]string{"stone", "copper"}, [2]float32{100, 20}, [2]float32{98 - 78*COOLING_RATE/4, 28}},
+		{"same temperature", [2]string{"copper", "copper"}, [2]float32{20, 20}, [2]float32{20, 20}},
+		{"cooling", [2]string{"copper", "felt"}, [2]float32{120, 20}, [2]float32{120 - 100*COOLING_RATE, 20}},
+		{"cooling more capacity", [2]string{"stone", "felt"}, [2]float32{120, 20}, [2]float32{120 - 100*COOLING_RATE/4, 20}},
+	}
+
+	folder := testData(t, heatElements)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			world, err := NewWorld(1, 1, 10, 10, 1, folder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer world.Close()
+			felt := lookup(t, world, "felt")
+			for _, chunk := range world.Chunks {
+				for i := range chunk.Cells {
+					chunk.Cells[i].SetType(felt)
+				}
+			}
+
+			var cells [2]*Cell
+			for i := range cells {
+				cells[i], _ = world.GetCell(5+i, 5)
+				cells[i].SetType(lookup(t, world, test.elements[i]))
+				cells[i].Temperature = test.temperatures[i]
+			}
+			cells[0].Conduct()
+
+			for i, cell := range cells {
+				if math.Abs(float64(cell.Temperature-test.expected[i])) > 1e-4 {
+					t.Errorf("cell %v is at %v degrees, expected %v", i, cell.Temperature, test.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAddHeat(t *testing.T) {
+	tests := []struct {
+		element  string
+		heat     float32
+		expected float32
+		wakes    bool
+	}{
+		{"copper", 10, 30, true},
+		{"copper", -10, 10, true},
+		{"stone", 10, 22.5, true},
+		{"copper", HEAT_THRESHOLD / 2, 20 + HEAT_THRESHOLD/2, false},
+		{"stone", HEAT_THRESHOLD * 2, 20 + HEAT_THRESHOLD/2, false},
+	}
+
+	folder := testData(t, heatElements)
+	world, err := NewWorld(1, 1, 10, 10, 1, folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer world.Close()
+	for _, test := range tests {
+		cell, _ := world.GetCell(5, 5)
+		cell.SetType(lookup(t, world, test.element))
+		cell.Temperature = 20
+		if err := world.Step(3); err != nil {
+			t.Fatal(err)
+		}
+
+		cell.AddHeat(test.heat)
+		if math.Abs(float64(cell.Temperature-test.expected)) > 1e-4 {
+			t.Errorf("%v of %v is at %v degrees, expected %v", test.heat, test.element, cell.Temperature, test.expected)
+		}
+		if wakes := !world.Chunks[0].Sleeping(); wakes != test.wakes {
+			t.Errorf("%v of %v woke the cell: %v, expected %v", test.heat, test.element, wakes, test.wakes)
+		}
+	}
+}
+
+func TestFireTemperatureStaysBounded(t *testing.T) {
+	world := newTestWorld(t, 2, 2, 10, 10)
+	canvas := NewCanvas(world)
+	canvas.Element = lookup(t, world, "fire")
+	canvas.FillRect(Rect{3, 3, 16, 16})
+
+	hottest := float32(0)
+	for range 150 {
+		if err := world.Step(1); err != nil {
+			t.Fatal(err)
+		}
+		for _, chunk := range world.Chunks {
+			for i := range chunk.Cells {
+				hottest = max(hottest, chunk.Cells[i].Temperature)
+			}
+		}
+	}
+	if fire := world.ElementData[lookup(t, world, "fire")].Temperature; hottest > fire*1.1 {
+		t.Errorf("the hottest cell reached %v degrees, while fire starts at %v", hottest, fire)
+	}
+}
+
+func TestPhaseTransitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		element     string
+		temperature float32
+		expected    string
+	}{
+		{"freezing", "water", -20, "ice"},
+		{"boiling", "water", 150, "steam"},
+		{"between", "water", 50, "water"},
+		{"melting", "ice", 20, "water"},
+		{"condensing", "steam", 50, "water"},
+		{"no transitions", "sand", 1000, "sand"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			world := newTestWorld(t, 1, 1, 10, 10)
+			cell, _ := world.GetCell(5, 5)
+			cell.SetType(lookup(t, world, test.element))
+			cell.Temperature = test.temperature
+			if err := world.Step(1); err != nil {
+				t.Fatal(err)
+			}
+
+			if count := countCells(world, lookup(t, world, test.expected)); count != 1 {
+				t.Errorf("%v cells of %v, expected %v at %v degrees to turn into one", count, test.expected, test.element, test.temperature)
+			}
+		})
+	}
+}
+
+func TestDefinePhaseTransitions(t *testing.T) {
+	folder := testData(t, map[string]string{"elements/chocolate.xml": `<element name="chocolate">
+  <immovable-solid />
+  <melts-at temp="30" into="sand" />
+  <melts-at temp="60" into="fire" />
+  <freezes-at temp="-10" into="ice" />
+</element>`})
+	world, err := NewWorld(1, 1, 10, 10, 1, folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer world.Close()
+
+	// Transitions are tried in order, so the lower of two rising ones has to
+	// come first to ever happen.
+	expected := []PhaseTransition{
+		{Rising: true, Temperature: 30, Into: lookup(t, world, "sand")},
+		{Rising: true, Temperature: 60, Into: lookup(t, world, "fire")},
+		{Rising: false, Temperature: -10, Into: lookup(t, world, "ice")},
+	}
+	transitions := world.ElementData[lookup(t, world, "chocolate")].Transitions
+	if len(transitions) != len(expected) {
+		t.Fatalf("got transitions %v, expected %v", transitions, expected)
+	}
+	for i := range transitions {
+		if transitions[i] != expected[i] {
+			t.Errorf("got transition %v, expected %v", transitions[i], expected[i])
+		}
+	}
+
+	folder = testData(t, map[string]string{"elements/chocolate.xml": `<element name="chocolate">
+  <immovable-solid />
+  <melts-at temp="30" into="cocoa" />
+</element>`})
+	_, err = ValidateElements(folder)
+	var diagnostics Diagnostics
+	if !errors.As(err, &diagnostics) || len(diagnostics) != 1 || diagnostics[0].Line != 3 {
+		t.Errorf("got %v, expected a diagnostic for the unknown element on line 3", err)
+	}
+}
@@ -0,0 +1,179 @@
+package game
+
+import (
+	"slices"
+	"unsafe"
+)
+
+// HISTORY_LIMIT is roughly how many bytes of cell states a History keeps
+// before it starts forgetting the oldest edits.
+const HISTORY_LIMIT = 64 << 20
+
+// CellState is everything an edit can change about the cell at X Y.
+type CellState struct {
+	X, Y        int
+	Type        int
+	Temperature float32
+	Data        []int32
+	BornAt      uint64
+}
+
+// Capture records the current state of a cell.
+func (cell *Cell) Capture() CellState {
+	return CellState{
+		X:           cell.WorldX(),
+		Y:           cell.WorldY(),
+		Type:        cell.Type,
+		Temperature: cell.Temperature,
+		Data:        slices.Clone(cell.Data()),
+		BornAt:      cell.BornAt,
+	}
+}
+
+// Size returns roughly how many bytes the state takes up.
+func (state CellState) Size() int {
+	return int(unsafe.Sizeof(state)) + len(state.Data)*4
+}
+
+// Restore puts the recorded state back into the world, waking the cell up
+// so that the simulation carries on from there.
+func (state CellState) Restore(world *World) {
+	cell, err := world.TouchCell(state.X, state.Y)
+	if err != nil {
+		return
+	}
+	cell.SetType(state.Type)
+	cell.SetTemperature(state.Temperature)
+	copy(cell.Data(), state.Data)
+	cell.BornAt = state.BornAt
+	cell.KeepAwake()
+}
+
+// Edit is a single stroke or tool operation: the state of every cell it
+// touched from before it started and from when it ended.
+type Edit struct {
+	Before []CellState
+	After  []CellState
+}
+
+// Size returns roughly how many bytes the states of the edit take up.
+func (edit *Edit) Size() int {
+	size := 0
+	for _, state := range edit.Before {
+		size += state.Size()
+	}
+	for _, state := range edit.After {
+		size += state.Size()
+	}
+	return size
+}
+
+// History keeps the edits made through a Canvas so they can be undone and
+// redone.
+type History struct {
+	Undos []*Edit
+	Redos []*Edit
+	// Limit is roughly how many bytes the edits can take up, see
+	// HISTORY_LIMIT.
+	Limit int
+
+	size    int
+	current *Edit
+	touched map[[2]int]bool
+}
+
+func NewHistory() *History {
+	return &History{Limit: HISTORY_LIMIT}
+}
+
+// Begin starts recording a new edit.
+func (history *History) Begin() {
+	history.current = &Edit{}
+	history.touched = map[[2]int]bool{}
+}
+
+// Record remembers the state of a cell before the current edit first
+// changes it.
+func (history *History) Record(cell *Cell) {
+	if history.current == nil {
+		return
+	}
+	position := [2]int{cell.WorldX(), cell.WorldY()}
+	if history.touched[position] {
+		return
+	}
+	history.touched[position] = true
+	history.current.Before = append(history.current.Before, cell.Capture())
+}
+
+// End finishes the current edit, capturing how the cells it touched look
+// now. Edits that didn't touch anything are dropped.
+func (history *History) End(world *World) {
+	edit := history.current
+	history.current = nil
+	history.touched = nil
+	if edit == nil || len(edit.Before) == 0 {
+		return
+	}
+
+	edit.After = make([]CellState, 0, len(edit.Before))
+	for _, state := range edit.Before {
+		if cell, err := world.GetCell(state.X, state.Y); err == nil {
+			edit.After = append(edit.After, cell.Capture())
+		}
+	}
+
+	history.Undos = append(history.Undos, edit)
+	history.size += edit.Size()
+	for _, redo := range history.Redos {
+		history.size -= redo.Size()
+	}
+	history.Redos = nil
+
+	for history.size > history.Limit && len(history.Undos) > 1 {
+		history.size -= history.Undos[0].Size()
+		history.Undos[0] = nil
+		history.Undos = history.Undos[1:]
+	}
+}
+
+// Undo restores the cells touched by the last edit to how they were before
+// it. It returns false if there is nothing to undo.
+func (history *History) Undo(world *World) bool {
+	if len(history.Undos) == 0 {
+		return false
+	}
+	edit := history.Undos[len(history.Undos)-1]
+	history.Undos = history.Undos[:len(history.Undos)-1]
+	history.Redos = append(history.Redos, edit)
+
+	for i := len(edit.Before) - 1; i >= 0; i-- {
+		edit.Before[i].Restore(world)
+	}
+	return true
+}
+
+// Redo paints the last undone edit again. It returns false if there is
+// nothing to redo.
+func (history *History) Redo(world *World) bool {
+	if len(history.Redos) == 0 {
+		return false
+	}
+	edit := history.Redos[len(history.Redos)-1]
+	history.Redos = history.Redos[:len(history.Redos)-1]
+	history.Undos = append(history.Undos, edit)
+
+	for _, state := range edit.After {
+		state.Restore(world)
+	}
+	return true
+}
+
+// Clear forgets every edit, for when the world is replaced.
+func (history *History) Clear() {
+	history.Undos = nil
+	history.Redos = nil
+	history.size = 0
+	history.current = nil
+	history.touched = nil
+}
@@ -0,0 +1,104 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+// cellStates captures every cell of the world, to compare worlds by their
+// cells alone.
+func cellStates(world *World) []CellState {
+	var states []CellState
+	for _, chunk := range world.Chunks {
+		for i := range chunk.Cells {
+			states = append(states, chunk.Cells[i].Capture())
+		}
+	}
+	return states
+}
+
+func TestUndoRedo(t *testing.T) {
+	world := newTestWorld(t, 2, 2, 10, 10)
+	canvas := NewCanvas(world)
+
+	edits := []struct {
+		element string
+		paint   func()
+	}{
+		{"sand", func() { canvas.Stamp(5, 5) }},
+		{"water", func() { canvas.Stroke(2, 2, 15, 9) }},
+		{"wood", func() { canvas.FillRect(Rect{3, 12, 16, 14}) }},
+		{"oil", func() { canvas.Fill(10, 17) }},
+	}
+
+	states := [][]CellState{cellStates(world)}
+	for _, edit := range edits {
+		canvas.Element = lookup(t, world, edit.element)
+		canvas.BeginEdit()
+		edit.paint()
+		canvas.EndEdit()
+		states = append(states, cellStates(world))
+	}
+
+	for i := len(edits) - 1; i >= 0; i-- {
+		if !canvas.Undo() {
+			t.Fatalf("nothing to undo after %v undos", len(edits)-1-i)
+		}
+		if !reflect.DeepEqual(cellStates(world), states[i]) {
+			t.Fatalf("undoing the %v edit left other cells than before it", edits[i].element)
+		}
+	}
+	if canvas.Undo() {
+		t.Error("undid more edits than were made")
+	}
+
+	for i := range edits {
+		if !canvas.Redo() {
+			t.Fatalf("nothing to redo after %v redos", i)
+		}
+		if !reflect.DeepEqual(cellStates(world), states[i+1]) {
+			t.Fatalf("redoing the %v edit left other cells than the edit did", edits[i].element)
+		}
+	}
+	if canvas.Redo() {
+		t.Error("redid more edits than were made")
+	}
+
+	// A new edit forgets what was undone.
+	canvas.Undo()
+	canvas.BeginEdit()
+	canvas.Plot(1, 1)
+	canvas.EndEdit()
+	if canvas.Redo() {
+		t.Error("redid an edit after making a new one")
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		kept  int
+	}{
+		{"everything", HISTORY_LIMIT, 5},
+		// Every edit plots one cell, so it holds two states.
+		{"the last edits", 2 * 2 * CellState{}.Size(), 2},
+		{"always the last edit", 1, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			world := newTestWorld(t, 1, 1, 10, 10)
+			canvas := NewCanvas(world)
+			canvas.History.Limit = test.limit
+			canvas.Element = lookup(t, world, "sand")
+			for x := 1; x <= 5; x++ {
+				canvas.BeginEdit()
+				canvas.Plot(x, 1)
+				canvas.EndEdit()
+			}
+			if kept := len(canvas.History.Undos); kept != test.kept {
+				t.Errorf("kept %v edits, expected %v", kept, test.kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package game
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"go-falling-sand/xml_handler"
+)
+
+// PaletteEntry maps a colour to the element it stands for in an image.
+type PaletteEntry struct {
+	Color   color.RGBA
+	Element int
+}
+
+type Palette []PaletteEntry
+
+// DefaultPalette maps every element to its display colour.
+func (w *World) DefaultPalette() Palette {
+	palette := make(Palette, 0, len(w.ElementData))
+	for id := range len(w.ElementData) {
+		palette = append(palette, PaletteEntry{w.ElementData[id].RGBA, id})
+	}
+	return palette
+}
+
+// LoadPalette reads a palette file such as
+//
+//	<palette>
+//	  <entry color="#ff0000" element="fire" />
+//	</palette>
+func (w *World) LoadPalette(in io.Reader) (Palette, error) {
+	var definition xmlhandler.XMLPalette
+	if err := xml.NewDecoder(in).Decode(&definition); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal palette: %v", err)
+	}
+
+	palette := make(Palette, 0, len(definition.Entries))
+	for _, entry := range definition.Entries {
+		col, err := StringToColor(entry.Color)
+		if err != nil {
+			return nil, fmt.Errorf("invalid palette color '%v': %v", entry.Color, err)
+		}
+		id, ok := w.LookupElement("", entry.Element)
+		if !ok {
+			return nil, fmt.Errorf("there is no element named '%v'", entry.Element)
+		}
+		palette = append(palette, PaletteEntry{color.RGBAModel.Convert(col).(color.RGBA), id})
+	}
+
+	if len(palette) == 0 {
+		return nil, fmt.Errorf("palette has no entries")
+	}
+
+	return palette, nil
+}
+
+// Nearest returns the element whose colour is closest to col.
+func (palette Palette) Nearest(col color.RGBA) int {
+	best := palette[0].Element
+	bestDistance := -1
+	for _, entry := range palette {
+		dr := int(entry.Color.R) - int(col.R)
+		dg := int(entry.Color.G) - int(col.G)
+		db := int(entry.Color.B) - int(col.B)
+		distance := dr*dr + dg*dg + db*db
+		if bestDistance == -1 || distance < bestDistance {
+			best = entry.Element
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+// Image returns a picture of the world with one pixel per cell. For
+// infinite worlds it covers the chunks that are loaded, and pixels keep the
+// world positions of their cells.
+func (w *World) Image() *image.RGBA {
+	worldBounds := w.Bounds()
+	img := image.NewRGBA(image.Rect(worldBounds.MinX, worldBounds.MinY, worldBounds.MaxX+1, worldBounds.MaxY+1))
+	pixels := make([]byte, w.ChunkArea()*4)
+	rowLength := w.ChunkWidth * 4
+
+	for _, chunk := range w.Chunks {
+		chunk.FillPixels(pixels)
+		bounds := chunk.WorldBounds()
+		for y := range w.ChunkHeight {
+			offset := img.PixOffset(bounds.MinX, bounds.MinY+y)
+			copy(img.Pix[offset:offset+rowLength], pixels[y*rowLength:(y+1)*rowLength])
+		}
+	}
+
+	return img
+}
+
+// ExportPNG writes a picture of the world to out as a PNG.
+func (w *World) ExportPNG(out io.Writer) error {
+	return png.Encode(out, w.Image())
+}
+
+// ImportImage paints img onto the world starting from its top left corner,
+// turning every pixel into the element of the nearest palette colour. Mostly
+// transparent pixels leave their cell alone and anything outside the world is
+// cut off, unless the world is infinite. A nil palette uses the colours of
+// the elements themselves.
+func (w *World) ImportImage(img image.Image, palette Palette) {
+	if palette == nil {
+		palette = w.DefaultPalette()
+	}
+
+	nearest := map[color.RGBA]int{}
+	bounds := img.Bounds()
+
+	width, height := bounds.Dx(), bounds.Dy()
+	if !w.Infinite {
+		width, height = min(width, w.TotalWidth()), min(height, w.TotalHeight())
+	}
+
+	for y := range height {
+		for x := range width {
+			col := color.RGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.RGBA)
+			if col.A < 128 {
+				continue
+			}
+
+			id, ok := nearest[col]
+			if !ok {
+				id = palette.Nearest(col)
+				nearest[col] = id
+			}
+
+			if cell, err := w.TouchCell(x, y); err == nil {
+				cell.Place(id)
+			}
+		}
+	}
+}
+
+// ImportPNG decodes a PNG from in and paints it onto the world, see
+// ImportImage.
+func (w *World) ImportPNG(in io.Reader, palette Palette) error {
+	img, err := png.Decode(in)
+	if err != nil {
+		return fmt.Errorf("failed to decode png: %v", err)
+	}
+	w.ImportImage(img, palette)
+	return nil
+}
@@ -0,0 +1,105 @@
+package game
+
+import (
+	"bytes"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestPNGRoundTrip(t *testing.T) {
+	world := newTestWorld(t, 2, 2, 10, 10)
+	for i, element := range []string{"sand", "water", "wood", "plant", "oil", "wax"} {
+		id := lookup(t, world, element)
+		for y := 4; y <= 15; y++ {
+			for x := 2 + i*3; x <= 4+i*3; x++ {
+				cell, _ := world.GetCell(x, y)
+				cell.SetType(id)
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := world.ExportPNG(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := newTestWorld(t, 2, 2, 10, 10)
+	if err := imported.ImportPNG(&buffer, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for y := range world.TotalHeight() {
+		for x := range world.TotalWidth() {
+			expected, _ := world.GetCell(x, y)
+			got, _ := imported.GetCell(x, y)
+			if got.Type != expected.Type {
+				t.Fatalf("cell %v %v is %v, expected %v", x, y,
+					imported.ElementData[got.Type].ElementTypeName, world.ElementData[expected.Type].ElementTypeName)
+			}
+		}
+	}
+}
+
+func TestPaletteNearest(t *testing.T) {
+	palette := Palette{
+		{color.RGBA{255, 0, 0, 255}, 1},
+		{color.RGBA{0, 255, 0, 255}, 2},
+		{color.RGBA{0, 0, 255, 255}, 3},
+		{color.RGBA{0, 0, 0, 255}, 4},
+	}
+
+	tests := []struct {
+		color    color.RGBA
+		expected int
+	}{
+		{color.RGBA{255, 0, 0, 255}, 1},
+		{color.RGBA{200, 60, 60, 255}, 1},
+		{color.RGBA{10, 240, 100, 255}, 2},
+		{color.RGBA{0, 0, 140, 255}, 3},
+		{color.RGBA{20, 20, 20, 255}, 4},
+		// Only the colour counts, not how transparent it is.
+		{color.RGBA{0, 0, 0, 0}, 4},
+	}
+
+	for _, test := range tests {
+		if got := palette.Nearest(test.color); got != test.expected {
+			t.Errorf("nearest to %v is %v, expected %v", test.color, got, test.expected)
+		}
+	}
+}
+
+func TestLoadPalette(t *testing.T) {
+	world := newTestWorld(t, 1, 1, 10, 10)
+
+	palette, err := world.LoadPalette(strings.NewReader(`<palette>
+  <entry color="#ff0000" element="fire" />
+  <entry color="#0000ff" element="water" />
+</palette>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Palette{
+		{color.RGBA{255, 0, 0, 255}, lookup(t, world, "fire")},
+		{color.RGBA{0, 0, 255, 255}, lookup(t, world, "water")},
+	}
+	if len(palette) != len(expected) {
+		t.Fatalf("got palette %v, expected %v", palette, expected)
+	}
+	for i := range palette {
+		if palette[i] != expected[i] {
+			t.Errorf("got entry %v, expected %v", palette[i], expected[i])
+		}
+	}
+
+	for name, file := range map[string]string{
+		"bad color":       `<palette><entry color="nope" element="fire" /></palette>`,
+		"unknown element": `<palette><entry color="red" element="lava" /></palette>`,
+		"no entries":      `<palette></palette>`,
+		"syntax error":    `<palette><entry color="red" element="fire"></palette>`,
+	} {
+		if _, err := world.LoadPalette(strings.NewReader(file)); err == nil {
+			t.Errorf("%v: expected an error", name)
+		}
+	}
+}
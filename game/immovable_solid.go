@@ -1,5 +1,7 @@
 package game
 
+import "math/rand"
+
 type ImmovableSolid struct{}
 
 func (ImmovableSolid) IsA(kind string) bool {
@@ -10,6 +12,6 @@ func (ImmovableSolid) Create(cell *Cell) error {
 	return nil
 }
 
-func (ImmovableSolid) Update(cell *Cell) error {
+func (ImmovableSolid) Update(cell *Cell, rng *rand.Rand) error {
 	return nil
 }
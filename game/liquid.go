@@ -12,19 +12,19 @@ func (Liquid) Create(cell *Cell) error {
 	return nil
 }
 
-func (Liquid) Update(cell *Cell) error {
+func (Liquid) Update(cell *Cell, rng *rand.Rand) error {
 	bottom, err := cell.GetCell(0, 1)
 	if err != nil {
 		return nil
 	}
 
 	if cell.CanFallInto(bottom) {
-		cell.Switch(bottom)
+		cell.Switch(bottom, rng)
 		return nil
 	}
 
 	dir := 1
-	if rand.Float32() < 0.5 {
+	if rng.Float32() < 0.5 {
 		dir *= -1
 	}
 
@@ -33,7 +33,7 @@ func (Liquid) Update(cell *Cell) error {
 		dir *= -1
 	} else {
 		if cell.CanMoveInto(bottomLeft) {
-			cell.Switch(bottomLeft)
+			cell.Switch(bottomLeft, rng)
 			return nil
 		} else {
 			dir *= -1
@@ -45,7 +45,7 @@ func (Liquid) Update(cell *Cell) error {
 		dir *= -1
 	} else {
 		if cell.CanMoveInto(bottomRight) {
-			cell.Switch(bottomRight)
+			cell.Switch(bottomRight, rng)
 			return nil
 		} else {
 			dir *= -1
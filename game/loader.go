@@ -0,0 +1,230 @@
+package game
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-falling-sand/xml_handler"
+)
+
+// LoadElements defines every element found in the xml files of dataFolder,
+// which can hold several folders separated by the OS list separator. Files
+// outside of any pack are loaded first, then packs after the packs they
+// depend on. It reports every problem it runs into as a Diagnostics error
+// rather than stopping at the first one.
+func (w *World) LoadElements(dataFolder string) error {
+	packs, packless, diagnostics := FindPacks(filepath.SplitList(dataFolder))
+	w.Packs = packs
+
+	results := make([]*xmlhandler.XMLElementDefinition, 0, 20)
+	load := func(pack string, files []string) {
+		for _, file := range files {
+			definitions, problems := ReadDefinitions(file)
+			diagnostics = append(diagnostics, problems...)
+			for _, definition := range definitions {
+				if strings.Contains(definition.Name, PACK_SEPARATOR) {
+					diagnostics = append(diagnostics, Diagnostic{
+						File:    file,
+						Line:    definition.Line,
+						Message: fmt.Sprintf("element name '%v' can't contain '%v'", definition.Name, PACK_SEPARATOR),
+					})
+					continue
+				}
+				if definition.Name != "" {
+					definition.Name = QualifiedName(pack, definition.Name)
+				}
+				definition.Pack = pack
+				results = append(results, definition)
+			}
+		}
+	}
+	load("", packless)
+	for _, pack := range packs {
+		load(pack.Name, pack.Files)
+	}
+
+	// Templates share their names with elements, as both can be extended.
+	unique := make([]*xmlhandler.XMLElementDefinition, 0, len(results))
+	byName := map[string]*xmlhandler.XMLElementDefinition{}
+	for _, result := range results {
+		if previous, ok := byName[result.Name]; ok && result.Name != "" {
+			diagnostics = append(diagnostics, Diagnostic{
+				File:    result.File,
+				Line:    result.Line,
+				Message: fmt.Sprintf("element '%v' is already defined at %v:%v", result.Name, previous.File, previous.Line),
+			})
+			continue
+		}
+		byName[result.Name] = result
+		unique = append(unique, result)
+
+		// Templates are never defined, so check them here.
+		if result.Template {
+			diagnostics = append(diagnostics, CheckTags(result).InFile(result.File)...)
+		}
+	}
+
+	for _, result := range unique {
+		if result.Extends == "" {
+			continue
+		}
+		if fullName, ok := w.ResolveName(result.Pack, result.Extends, func(fullName string) bool {
+			_, ok := byName[fullName]
+			return ok
+		}); ok {
+			result.Extends = fullName
+		}
+	}
+
+	resolved, problems := ResolveTemplates(unique)
+	diagnostics = append(diagnostics, problems...)
+
+	defined := make([]*xmlhandler.XMLElementDefinition, 0, len(resolved))
+	roles := map[string]*xmlhandler.XMLElementDefinition{}
+
+	for _, result := range resolved {
+		if err := w.HandleCommand(result); err != nil {
+			var other Diagnostics
+			other.Merge(result.Line, err)
+			diagnostics = append(diagnostics, other.InFile(result.File)...)
+		}
+
+		// Elements with problems are still defined when they have a name,
+		// and their reactions are worth checking too.
+		if _, ok := w.ElementTypes[result.Name]; !ok {
+			continue
+		}
+		defined = append(defined, result)
+
+		if result.Role == ROLE_AIR || result.Role == ROLE_WALL {
+			if previous, ok := roles[result.Role]; ok {
+				diagnostics = append(diagnostics, Diagnostic{
+					File:    result.File,
+					Line:    result.Line,
+					Message: fmt.Sprintf("'%v' has role '%v', but so does '%v' at %v:%v", result.Name, result.Role, previous.Name, previous.File, previous.Line),
+				})
+			}
+			roles[result.Role] = result
+		}
+	}
+
+	// Definitions that couldn't be resolved were reported already. Define
+	// them bare, so reactions naming them don't fail because of it.
+	names := map[string]bool{}
+	for _, result := range resolved {
+		names[result.Name] = true
+	}
+	for _, result := range unique {
+		if result.Template || result.Name == "" || names[result.Name] {
+			continue
+		}
+		bare := &xmlhandler.XMLElementDefinition{Name: result.Name, Line: result.Line, Pack: result.Pack}
+		w.DefineElement(bare, bare.Name, "white", LocalName(bare.Name), ROLE_NONE, false, 0)
+	}
+
+	for _, result := range defined {
+		if err := w.HandleCommandReaction(result); err != nil {
+			var other Diagnostics
+			other.Merge(result.Line, err)
+			diagnostics = append(diagnostics, other.InFile(result.File)...)
+		}
+	}
+
+	for _, role := range []string{ROLE_AIR, ROLE_WALL} {
+		if _, ok := roles[role]; !ok {
+			diagnostics = append(diagnostics, Diagnostic{
+				File:    dataFolder,
+				Message: fmt.Sprintf("no element has the role '%v'", role),
+			})
+		}
+	}
+
+	// Inherited reactions report their problems for every element inheriting
+	// them, so leave only the first report.
+	seen := map[Diagnostic]bool{}
+	reported := diagnostics[:0]
+	for _, diagnostic := range diagnostics {
+		if !seen[diagnostic] {
+			seen[diagnostic] = true
+			reported = append(reported, diagnostic)
+		}
+	}
+	return reported.Err()
+}
+
+// ReadDefinitions reads the element definitions of a file, which holds
+// either a single <element> or several inside of <elements>.
+func ReadDefinitions(file string) ([]*xmlhandler.XMLElementDefinition, Diagnostics) {
+	fail := func(err error, message string) Diagnostics {
+		diagnostic := Diagnostic{File: file, Message: fmt.Sprintf("%v: %v", message, err)}
+		var syntaxError *xml.SyntaxError
+		if errors.As(err, &syntaxError) {
+			diagnostic.Line = syntaxError.Line
+			diagnostic.Message = syntaxError.Msg
+		}
+		return Diagnostics{diagnostic}
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fail(err, "failed to read file")
+	}
+
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, fail(err, "failed to unmarshal file")
+	}
+
+	switch root {
+	case "element":
+		definition := &xmlhandler.XMLElementDefinition{}
+		if err := xml.Unmarshal(data, definition); err != nil {
+			return nil, fail(err, "failed to unmarshal file")
+		}
+		definition.File = file
+		return []*xmlhandler.XMLElementDefinition{definition}, nil
+	case "elements":
+		var list xmlhandler.XMLElementList
+		if err := xml.Unmarshal(data, &list); err != nil {
+			return nil, fail(err, "failed to unmarshal file")
+		}
+		definitions := make([]*xmlhandler.XMLElementDefinition, len(list.Elements))
+		for i := range list.Elements {
+			list.Elements[i].File = file
+			definitions[i] = &list.Elements[i]
+		}
+		return definitions, nil
+	default:
+		return nil, Diagnostics{{File: file, Message: fmt.Sprintf("unknown root tag <%v>, expected <element> or <elements>", root)}}
+	}
+}
+
+// rootElement returns the name of the outermost tag of an xml document.
+func rootElement(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// ValidateElements loads the element definitions of dataFolder without
+// creating a world, and returns every problem found in them.
+func ValidateElements(dataFolder string) (int, error) {
+	world := &World{}
+	world.ElementData = map[int]*ElementData{}
+	world.ElementTypes = map[string]int{}
+
+	err := world.LoadElements(dataFolder)
+	return len(world.ElementData), err
+}
@@ -0,0 +1,114 @@
+package game
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadElementsDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		expected []Diagnostic
+	}{
+		{
+			name: "unknown element in reaction",
+			file: `<element name="bad">
+  <movable-solid />
+  <reactions>
+    <reaction>
+      <touching>nothing</touching>
+      <turn-into>sand</turn-into>
+    </reaction>
+  </reactions>
+</element>`,
+			expected: []Diagnostic{{Line: 5, Message: "there is no element named 'nothing'"}},
+		},
+		{
+			name: "unknown tag in reaction",
+			file: `<element name="bad">
+  <reactions>
+    <reaction>
+      <teleport />
+    </reaction>
+  </reactions>
+</element>`,
+			expected: []Diagnostic{{Line: 4, Message: "unknown reaction step <teleport>"}},
+		},
+		{
+			name: "every problem of an element",
+			file: `<element name="bad" role="ghost">
+  <colour>red</colour>
+  <display>
+    <color>nope</color>
+  </display>
+  <reactions>
+    <reaction>
+      <chance>often</chance>
+    </reaction>
+  </reactions>
+</element>`,
+			expected: []Diagnostic{
+				{Line: 2, Message: "unknown tag <colour> in <element>"},
+				{Line: 3, Message: "invalid color 'nope': invalid color name: 'nope'"},
+				{Line: 1, Message: "unknown role 'ghost', expected 'air', 'wall' or 'none'"},
+				{Line: 8, Message: `error while parsing float in xml: strconv.ParseFloat: parsing "often": invalid syntax`},
+			},
+		},
+		{
+			name: "reactions naming a broken element",
+			file: `<elements>
+  <element name="broken">
+    <display>
+      <color>nope</color>
+    </display>
+  </element>
+  <element name="orphan" extends="ghost" />
+  <element name="bad">
+    <reactions>
+      <reaction>
+        <touching>broken</touching>
+        <turn-into>orphan</turn-into>
+      </reaction>
+    </reactions>
+  </element>
+</elements>`,
+			expected: []Diagnostic{
+				{Line: 7, Message: "'orphan' extends 'ghost', which is not defined"},
+				{Line: 3, Message: "invalid color 'nope': invalid color name: 'nope'"},
+			},
+		},
+		{
+			name: "syntax error",
+			file: `<element name="bad">
+  <movable-solid>
+</element>`,
+			expected: []Diagnostic{{Line: 3, Message: "element <movable-solid> closed by </element>"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := testData(t, map[string]string{"elements/bad.xml": test.file})
+			_, err := ValidateElements(folder)
+
+			var diagnostics Diagnostics
+			if !errors.As(err, &diagnostics) {
+				t.Fatalf("expected diagnostics, got %v", err)
+			}
+			file := filepath.Join(folder, "elements", "bad.xml")
+			for i := range test.expected {
+				test.expected[i].File = file
+			}
+			if len(diagnostics) != len(test.expected) {
+				t.Fatalf("got diagnostics\n%v\nexpected\n%v", diagnostics, Diagnostics(test.expected))
+			}
+			for i, diagnostic := range diagnostics {
+				if diagnostic != test.expected[i] {
+					t.Errorf("got %v, expected %v", diagnostic, test.expected[i])
+				}
+			}
+		})
+	}
+}
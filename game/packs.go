@@ -0,0 +1,266 @@
+package game
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go-falling-sand/xml_handler"
+)
+
+// PACK_MANIFEST is the file that turns the folder it is in into a pack.
+const PACK_MANIFEST = "pack.xml"
+
+// PACK_SEPARATOR separates the name of a pack from the names of its
+// elements, as in "basic:sand".
+const PACK_SEPARATOR = ":"
+
+// Pack is a folder of element definitions sharing a namespace. Elements
+// of a pack are named "pack:element", and can refer to the elements of their
+// own pack and of the packs they depend on without the prefix.
+type Pack struct {
+	Name         string
+	Version      string
+	Dependencies []string
+	Folder       string
+	// Files are the definition files of the pack, not counting the ones of
+	// packs nested inside of it.
+	Files []string
+}
+
+// QualifiedName returns the full name of an element of a pack. Elements
+// outside of any pack keep their name.
+func QualifiedName(pack, name string) string {
+	if pack == "" {
+		return name
+	}
+	return pack + PACK_SEPARATOR + name
+}
+
+// LocalName strips the pack from the full name of an element.
+func LocalName(name string) string {
+	if i := strings.LastIndex(name, PACK_SEPARATOR); i >= 0 {
+		return name[i+len(PACK_SEPARATOR):]
+	}
+	return name
+}
+
+// LookupElement finds the element called name from within pack, see
+// ResolveName.
+func (w *World) LookupElement(pack, name string) (int, bool) {
+	fullName, ok := w.ResolveName(pack, name, func(fullName string) bool {
+		_, ok := w.ElementTypes[fullName]
+		return ok
+	})
+	if !ok {
+		return 0, false
+	}
+	return w.ElementTypes[fullName], true
+}
+
+// lookupFrom finds the element called name from within the pack of element.
+func (w *World) lookupFrom(element int, name string) (int, bool) {
+	return w.LookupElement(w.ElementData[element].Pack, name)
+}
+
+// ResolveName returns the full name of the element called name from within
+// pack, with exists telling which full names are taken. Names with a pack
+// are taken as they are. Other names are looked up in pack itself, then in
+// its dependencies in order and then outside of any pack. From outside of
+// any pack, a name is also found if exactly one pack has an element by
+// that name, so that "sand" still means "basic:sand".
+func (w *World) ResolveName(pack, name string, exists func(fullName string) bool) (string, bool) {
+	if strings.Contains(name, PACK_SEPARATOR) {
+		return name, exists(name)
+	}
+
+	if pack != "" {
+		candidates := []string{pack}
+		for _, other := range w.Packs {
+			if other.Name == pack {
+				candidates = append(candidates, other.Dependencies...)
+			}
+		}
+		for _, candidate := range candidates {
+			if fullName := QualifiedName(candidate, name); exists(fullName) {
+				return fullName, true
+			}
+		}
+	}
+
+	if exists(name) {
+		return name, true
+	}
+	if pack != "" {
+		return "", false
+	}
+
+	found := ""
+	for _, other := range w.Packs {
+		if fullName := QualifiedName(other.Name, name); exists(fullName) {
+			if found != "" {
+				return "", false
+			}
+			found = fullName
+		}
+	}
+	return found, found != ""
+}
+
+// FindPacks looks for packs and definition files in the given folders. It
+// returns the packs in the order they have to be loaded in, dependencies
+// first, and the definition files that aren't part of any pack.
+func FindPacks(folders []string) ([]*Pack, []string, Diagnostics) {
+	var diagnostics Diagnostics
+	var packs []*Pack
+	var files []string
+
+	for _, folder := range folders {
+		err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".xml") {
+				return nil
+			}
+			if d.Name() != PACK_MANIFEST {
+				files = append(files, path)
+				return nil
+			}
+
+			pack, err := ReadPack(path)
+			if err != nil {
+				diagnostics = append(diagnostics, Diagnostic{File: path, Message: err.Error()})
+				return nil
+			}
+			if index := slices.IndexFunc(packs, func(other *Pack) bool { return other.Name == pack.Name }); index >= 0 {
+				diagnostics = append(diagnostics, Diagnostic{
+					File:    path,
+					Message: fmt.Sprintf("pack '%v' is already defined in %v", pack.Name, packs[index].Folder),
+				})
+				return nil
+			}
+			packs = append(packs, pack)
+			return nil
+		})
+		if err != nil {
+			diagnostics = append(diagnostics, Diagnostic{File: folder, Message: fmt.Sprintf("error while getting xml files: %v", err)})
+		}
+	}
+
+	// Every file belongs to the innermost pack around it.
+	packless := make([]string, 0, len(files))
+	for _, file := range files {
+		var owner *Pack
+		for _, pack := range packs {
+			if isInside(file, pack.Folder) && (owner == nil || len(pack.Folder) > len(owner.Folder)) {
+				owner = pack
+			}
+		}
+		if owner == nil {
+			packless = append(packless, file)
+		} else {
+			owner.Files = append(owner.Files, file)
+		}
+	}
+
+	ordered, problems := sortPacks(packs)
+	return ordered, packless, append(diagnostics, problems...)
+}
+
+// ReadPack reads the manifest of a pack:
+//
+//	<pack name="basic" version="1.0">
+//	  <depends>standard</depends>
+//	</pack>
+func ReadPack(path string) (*Pack, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack manifest: %v", err)
+	}
+	var manifest xmlhandler.XMLPack
+	if err := xml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pack manifest: %v", err)
+	}
+	if manifest.Name == "" {
+		return nil, errors.New("pack has no name")
+	}
+	if strings.Contains(manifest.Name, PACK_SEPARATOR) {
+		return nil, fmt.Errorf("pack name '%v' can't contain '%v'", manifest.Name, PACK_SEPARATOR)
+	}
+	return &Pack{
+		Name:         manifest.Name,
+		Version:      manifest.Version,
+		Dependencies: manifest.Dependencies,
+		Folder:       filepath.Dir(path),
+	}, nil
+}
+
+// sortPacks orders packs so that every pack comes after its dependencies,
+// keeping the order they were found in otherwise. Packs with missing
+// dependencies or in a cycle are reported, and loaded last.
+func sortPacks(packs []*Pack) ([]*Pack, Diagnostics) {
+	var diagnostics Diagnostics
+	byName := map[string]*Pack{}
+	for _, pack := range packs {
+		byName[pack.Name] = pack
+	}
+
+	for _, pack := range packs {
+		for _, dependency := range pack.Dependencies {
+			if _, ok := byName[dependency]; !ok {
+				diagnostics = append(diagnostics, Diagnostic{
+					File:    filepath.Join(pack.Folder, PACK_MANIFEST),
+					Message: fmt.Sprintf("pack '%v' depends on '%v', which was not found", pack.Name, dependency),
+				})
+			}
+		}
+	}
+
+	ordered := make([]*Pack, 0, len(packs))
+	loaded := map[string]bool{}
+	for len(ordered) < len(packs) {
+		progress := false
+		for _, pack := range packs {
+			if loaded[pack.Name] {
+				continue
+			}
+			ready := true
+			for _, dependency := range pack.Dependencies {
+				if _, ok := byName[dependency]; ok && !loaded[dependency] {
+					ready = false
+				}
+			}
+			if ready {
+				ordered = append(ordered, pack)
+				loaded[pack.Name] = true
+				progress = true
+			}
+		}
+		if progress {
+			continue
+		}
+
+		for _, pack := range packs {
+			if !loaded[pack.Name] {
+				diagnostics = append(diagnostics, Diagnostic{
+					File:    filepath.Join(pack.Folder, PACK_MANIFEST),
+					Message: fmt.Sprintf("the dependencies of pack '%v' form a cycle", pack.Name),
+				})
+				ordered = append(ordered, pack)
+				loaded[pack.Name] = true
+			}
+		}
+	}
+	return ordered, diagnostics
+}
+
+func isInside(path, folder string) bool {
+	relative, err := filepath.Rel(folder, path)
+	return err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,202 @@
+package game
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestResolveName(t *testing.T) {
+	world := &World{Packs: []*Pack{
+		{Name: "standard"},
+		{Name: "basic", Dependencies: []string{"standard"}},
+		{Name: "fx", Dependencies: []string{"basic"}},
+		{Name: "other"},
+	}}
+	names := []string{"standard:air", "basic:sand", "basic:water", "fx:sand", "fx:spark", "other:spark", "loose"}
+	exists := func(name string) bool { return slices.Contains(names, name) }
+
+	tests := []struct {
+		pack, name string
+		expected   string
+	}{
+		{"basic", "sand", "basic:sand"},
+		{"basic", "air", "standard:air"},
+		{"fx", "sand", "fx:sand"},
+		{"fx", "water", "basic:water"},
+		// Only direct dependencies are looked in.
+		{"fx", "air", ""},
+		{"fx", "loose", "loose"},
+		{"fx", "other:spark", "other:spark"},
+		{"basic", "spark", ""},
+		{"", "water", "basic:water"},
+		{"", "loose", "loose"},
+		// More than one pack has these.
+		{"", "sand", ""},
+		{"", "spark", ""},
+		{"", "basic:nothing", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.pack+" "+test.name, func(t *testing.T) {
+			name, ok := world.ResolveName(test.pack, test.name, exists)
+			if ok != (test.expected != "") || ok && name != test.expected {
+				t.Errorf("resolved to '%v' %v, expected '%v'", name, ok, test.expected)
+			}
+		})
+	}
+}
+
+func TestFindPacks(t *testing.T) {
+	tests := []struct {
+		name        string
+		manifests   map[string]string
+		order       []string
+		diagnostics []string
+	}{
+		{
+			name: "dependencies first",
+			manifests: map[string]string{
+				"a": `<pack name="a"><depends>c</depends></pack>`,
+				"b": `<pack name="b"><depends>a</depends><depends>c</depends></pack>`,
+				"c": `<pack name="c" />`,
+			},
+			order: []string{"c", "a", "b"},
+		},
+		{
+			name: "missing dependency",
+			manifests: map[string]string{
+				"a": `<pack name="a"><depends>ghost</depends></pack>`,
+			},
+			order:       []string{"a"},
+			diagnostics: []string{"pack 'a' depends on 'ghost', which was not found"},
+		},
+		{
+			name: "cycle",
+			manifests: map[string]string{
+				"a": `<pack name="a"><depends>b</depends></pack>`,
+				"b": `<pack name="b"><depends>a</depends></pack>`,
+				"c": `<pack name="c" />`,
+			},
+			order: []string{"c", "a", "b"},
+			diagnostics: []string{
+				"the dependencies of pack 'a' form a cycle",
+				"the dependencies of pack 'b' form a cycle",
+			},
+		},
+		{
+			name: "duplicate and bad names",
+			manifests: map[string]string{
+				"a": `<pack name="a" />`,
+				"b": `<pack name="a" />`,
+				"c": `<pack name="c:d" />`,
+				"d": `<pack />`,
+			},
+			order: []string{"a"},
+			diagnostics: []string{
+				"pack 'a' is already defined in FOLDER/a",
+				"pack name 'c:d' can't contain ':'",
+				"pack has no name",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := t.TempDir()
+			files := map[string]string{"loose.xml": `<element name="loose" />`}
+			for name, manifest := range test.manifests {
+				files[filepath.Join(name, PACK_MANIFEST)] = manifest
+				files[filepath.Join(name, "element.xml")] = `<element name="e" />`
+			}
+			writeFiles(t, folder, files)
+
+			packs, packless, diagnostics := FindPacks([]string{folder})
+
+			var order []string
+			for _, pack := range packs {
+				order = append(order, pack.Name)
+				if len(pack.Files) != 1 || filepath.Dir(pack.Files[0]) != pack.Folder {
+					t.Errorf("pack '%v' has files %v", pack.Name, pack.Files)
+				}
+			}
+			if !slices.Equal(order, test.order) {
+				t.Errorf("got packs %v, expected %v", order, test.order)
+			}
+			if !slices.Contains(packless, filepath.Join(folder, "loose.xml")) {
+				t.Errorf("loose.xml isn't among the files outside of packs %v", packless)
+			}
+
+			var messages []string
+			for _, diagnostic := range diagnostics {
+				messages = append(messages, strings.ReplaceAll(diagnostic.Message, folder, "FOLDER"))
+			}
+			if !slices.Equal(messages, test.diagnostics) {
+				t.Errorf("got diagnostics %q, expected %q", messages, test.diagnostics)
+			}
+		})
+	}
+}
+
+func TestLoadPacks(t *testing.T) {
+	extra := t.TempDir()
+	writeFiles(t, extra, map[string]string{
+		"fx/pack.xml": `<pack name="fx" version="0.1">
+  <depends>basic</depends>
+</pack>`,
+		"fx/things.xml": `<elements>
+  <element name="ash">
+    <movable-solid />
+  </element>
+  <element name="sand">
+    <movable-solid />
+    <reactions>
+      <reaction>
+        <touching>water</touching>
+        <turn-into>ash</turn-into>
+      </reaction>
+    </reactions>
+  </element>
+  <element name="glass" extends="basic:sand">
+    <reactions>
+      <reaction>
+        <touching>basic:sand</touching>
+        <turn-into>sand</turn-into>
+      </reaction>
+    </reactions>
+  </element>
+</elements>`,
+	})
+
+	world, err := NewWorld(1, 1, 10, 10, 1, "../data"+string(filepath.ListSeparator)+extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer world.Close()
+
+	lookups := []struct {
+		pack, name string
+		expected   string
+	}{
+		{"", "air", "standard:air"},
+		{"", "glass", "fx:glass"},
+		{"", "basic:sand", "basic:sand"},
+		{"fx", "sand", "fx:sand"},
+		{"basic", "sand", "basic:sand"},
+		{"fx", "water", "basic:water"},
+	}
+	for _, lookup := range lookups {
+		id, ok := world.LookupElement(lookup.pack, lookup.name)
+		if !ok || world.ElementData[id].ElementTypeName != lookup.expected {
+			t.Errorf("'%v' from pack '%v' isn't '%v'", lookup.name, lookup.pack, lookup.expected)
+		}
+	}
+
+	glass := world.ElementData[world.ElementTypes["fx:glass"]]
+	if glass.Name != "glass" || glass.Pack != "fx" {
+		t.Errorf("glass is named '%v' in pack '%v'", glass.Name, glass.Pack)
+	}
+	if _, ok := glass.Kind.(*MovableSolid); !ok {
+		t.Errorf("glass didn't inherit being a movable solid from basic:sand")
+	}
+}
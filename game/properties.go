@@ -0,0 +1,51 @@
+package game
+
+// Property is a named integer that every cell of an element carries around,
+// like the fuel left in a burning cell. The value of a property lives in
+// the data slot matching its position in ElementData.Properties.
+type Property struct {
+	Name    string
+	Default int32
+}
+
+// PropertySlot returns the data slot of the named property of the element.
+func (data *ElementData) PropertySlot(name string) (int, bool) {
+	for slot, property := range data.Properties {
+		if property.Name == name {
+			return slot, true
+		}
+	}
+	return 0, false
+}
+
+// Data returns the property values of the cell, indexed by slot. The values
+// are stored in the chunk, next to those of the other cells, so the slice
+// stays valid for as long as the chunk does.
+func (cell *Cell) Data() []int32 {
+	slots := cell.World().DataSlots
+	if slots == 0 {
+		return nil
+	}
+	i := cell.Index() * slots
+	return cell.Chunk.Data[i : i+slots : i+slots]
+}
+
+// ResetData sets the properties of the cell to the defaults of its element.
+func (cell *Cell) ResetData() {
+	data := cell.Data()
+	if data == nil {
+		return
+	}
+	clear(data)
+	for slot, property := range cell.ElementData().Properties {
+		data[slot] = property.Default
+	}
+}
+
+// swapData swaps the properties of two cells, as they swap places.
+func (cell *Cell) swapData(other *Cell) {
+	data, otherData := cell.Data(), other.Data()
+	for i := range data {
+		data[i], otherData[i] = otherData[i], data[i]
+	}
+}
@@ -0,0 +1,396 @@
+package game
+
+import (
+	"testing"
+)
+
+// reactionTest paints elements into a small world, steps it and counts the
+// elements it ends up with.
+type reactionTest struct {
+	name string
+	// elements holds the <element>s the test adds to the bundled ones.
+	elements string
+	paint    []paint
+	ticks    int
+	expected map[string]int
+	check    func(t *testing.T, world *World)
+}
+
+type paint struct {
+	rect    Rect
+	element string
+}
+
+func runReactionTests(t *testing.T, tests []reactionTest) {
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := testData(t, map[string]string{
+				"elements/test.xml": "<elements>\n" + test.elements + "\n</elements>",
+			})
+			world, err := NewWorld(2, 2, 10, 10, 1, folder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer world.Close()
+
+			canvas := NewCanvas(world)
+			for _, paint := range test.paint {
+				canvas.Element = lookup(t, world, paint.element)
+				canvas.FillRect(paint.rect)
+			}
+			if err := world.Step(test.ticks); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, expected := range test.expected {
+				if count := countCells(world, lookup(t, world, name)); count != expected {
+					t.Errorf("%v cells of %v after %v ticks, expected %v", count, name, test.ticks, expected)
+				}
+			}
+			if test.check != nil {
+				test.check(t, world)
+			}
+		})
+	}
+}
+
+func TestPropertyReactions(t *testing.T) {
+	fuse := `<element name="fuse">
+  <immovable-solid />
+  <property name="left" default="5" />
+  <reactions>
+    <reaction>
+      <subtract-property name="left" />
+    </reaction>
+    <reaction>
+      <property name="left" le="0" />
+      <turn-into>air</turn-into>
+    </reaction>
+  </reactions>
+</element>`
+
+	runReactionTests(t, []reactionTest{
+		{
+			name: "set and compare",
+			elements: `<element name="flag">
+  <immovable-solid />
+  <property name="on" />
+  <reactions>
+    <reaction>
+      <property name="on" eq="1" />
+      <turn-into>sand</turn-into>
+    </reaction>
+    <reaction>
+      <set-property name="on" value="1" />
+    </reaction>
+  </reactions>
+</element>`,
+			paint:    []paint{{Rect{5, 5, 5, 5}, "flag"}},
+			ticks:    3,
+			expected: map[string]int{"flag": 0, "sand": 1},
+		},
+		{
+			name:     "counting down",
+			elements: fuse,
+			paint:    []paint{{Rect{5, 5, 7, 5}, "fuse"}},
+			ticks:    4,
+			expected: map[string]int{"fuse": 3},
+		},
+		{
+			name:     "counted down",
+			elements: fuse,
+			paint:    []paint{{Rect{5, 5, 7, 5}, "fuse"}},
+			ticks:    5,
+			expected: map[string]int{"fuse": 0},
+		},
+		{
+			name: "age",
+			elements: `<element name="rot">
+  <immovable-solid />
+  <reactions>
+    <reaction>
+      <age ge="5" />
+      <turn-into>sand</turn-into>
+    </reaction>
+  </reactions>
+</element>`,
+			paint:    []paint{{Rect{5, 5, 6, 5}, "rot"}},
+			ticks:    8,
+			expected: map[string]int{"rot": 0, "sand": 2},
+		},
+		{
+			name: "properties move with the cell",
+			elements: `<element name="marked">
+  <movable-solid />
+  <material>
+    <density>5</density>
+  </material>
+  <property name="mark" />
+  <reactions>
+    <reaction>
+      <set-property name="mark" value="7" />
+    </reaction>
+  </reactions>
+</element>`,
+			paint: []paint{{Rect{5, 2, 5, 2}, "marked"}},
+			ticks: 30,
+			check: func(t *testing.T, world *World) {
+				cell, _ := world.GetCell(5, 18)
+				if cell.Type != lookup(t, world, "marked") {
+					t.Fatal("the marked cell didn't fall to the bottom")
+				}
+				if data := cell.Data(); data[0] != 7 {
+					t.Errorf("the mark of the fallen cell is %v, expected 7", data[0])
+				}
+			},
+		},
+	})
+}
+
+func TestNeighbourConditions(t *testing.T) {
+	sensor := func(condition string) string {
+		return `<element name="sensor">
+  <immovable-solid />
+  <reactions>
+    <reaction>
+      ` + condition + `
+      <turn-into>wall</turn-into>
+    </reaction>
+  </reactions>
+</element>`
+	}
+
+	runReactionTests(t, []reactionTest{
+		{
+			name:     "below",
+			elements: sensor(`<below>wood</below>`),
+			paint: []paint{
+				{Rect{3, 5, 8, 5}, "sensor"},
+				{Rect{3, 6, 5, 6}, "wood"},
+			},
+			ticks:    2,
+			expected: map[string]int{"sensor": 3},
+		},
+		{
+			name:     "above",
+			elements: sensor(`<above>wood</above>`),
+			paint: []paint{
+				{Rect{3, 5, 8, 5}, "sensor"},
+				{Rect{3, 6, 5, 6}, "wood"},
+			},
+			ticks:    2,
+			expected: map[string]int{"sensor": 6},
+		},
+		{
+			name:     "at least",
+			elements: sensor(`<neighbours of="wood" min="3" />`),
+			paint: []paint{
+				{Rect{4, 4, 6, 4}, "wood"},
+				{Rect{5, 5, 5, 5}, "sensor"},
+				{Rect{11, 4, 12, 4}, "wood"},
+				{Rect{12, 5, 12, 5}, "sensor"},
+			},
+			ticks:    2,
+			expected: map[string]int{"sensor": 1},
+		},
+		{
+			name:     "at most",
+			elements: sensor(`<neighbours of="wood" max="1" />`),
+			paint: []paint{
+				{Rect{4, 4, 6, 4}, "wood"},
+				{Rect{5, 5, 5, 5}, "sensor"},
+				{Rect{11, 4, 11, 4}, "wood"},
+				{Rect{12, 5, 12, 5}, "sensor"},
+			},
+			ticks:    2,
+			expected: map[string]int{"sensor": 1},
+		},
+		{
+			name:     "von neumann",
+			elements: sensor(`<neighbours of="wood" min="1" neighbourhood="von-neumann" />`),
+			paint: []paint{
+				{Rect{4, 4, 4, 4}, "wood"},
+				{Rect{5, 5, 5, 5}, "sensor"},
+				{Rect{12, 4, 12, 4}, "wood"},
+				{Rect{12, 5, 12, 5}, "sensor"},
+			},
+			ticks:    2,
+			expected: map[string]int{"sensor": 1},
+		},
+	})
+}
+
+func TestNeighbourActions(t *testing.T) {
+	cellIs := func(x, y int, name string) func(t *testing.T, world *World) {
+		return func(t *testing.T, world *World) {
+			t.Helper()
+			cell, _ := world.GetCell(x, y)
+			if cell.Type != lookup(t, world, name) {
+				t.Errorf("cell %v %v is %v, expected %v", x, y, cell.ElementData().ElementTypeName, name)
+			}
+		}
+	}
+
+	runReactionTests(t, []reactionTest{
+		{
+			name: "convert",
+			elements: `<element name="infector">
+  <immovable-solid />
+  <reactions>
+    <reaction>
+      <touching>wood</touching>
+      <convert-neighbour>wall</convert-neighbour>
+    </reaction>
+  </reactions>
+</element>`,
+			paint: []paint{
+				{Rect{5, 5, 5, 5}, "infector"},
+				{Rect{4, 6, 6, 7}, "wood"},
+			},
+			ticks:    10,
+			expected: map[string]int{"wood": 3, "infector": 1},
+		},
+		{
+			name: "consume",
+			elements: `<element name="eater">
+  <immovable-solid />
+  <reactions>
+    <reaction>
+      <directly-touching>wood</directly-touching>
+      <consume-neighbour />
+    </reaction>
+  </reactions>
+</element>`,
+			paint: []paint{
+				{Rect{4, 4, 6, 6}, "wood"},
+				{Rect{5, 5, 5, 5}, "eater"},
+			},
+			ticks:    10,
+			expected: map[string]int{"wood": 4, "eater": 1},
+		},
+		{
+			name: "swap",
+			elements: `<element name="bubble">
+  <immovable-solid />
+  <reactions>
+    <reaction>
+      <above>wood</above>
+      <swap-with>wood</swap-with>
+    </reaction>
+  </reactions>
+</element>`,
+			paint: []paint{
+				{Rect{5, 5, 5, 5}, "bubble"},
+				{Rect{5, 4, 5, 4}, "wood"},
+			},
+			ticks: 10,
+			check: func(t *testing.T, world *World) {
+				cellIs(5, 4, "bubble")(t, world)
+				cellIs(5, 5, "wood")(t, world)
+			},
+		},
+		{
+			name: "directed emit",
+			elements: `<element name="emitter">
+  <immovable-solid />
+  <reactions>
+    <reaction>
+      <emit dir="up-right">wood</emit>
+    </reaction>
+  </reactions>
+</element>`,
+			paint:    []paint{{Rect{5, 5, 5, 5}, "emitter"}},
+			ticks:    5,
+			expected: map[string]int{"wood": 1},
+			check:    cellIs(6, 4, "wood"),
+		},
+	})
+}
+
+func TestTagConditions(t *testing.T) {
+	shiny := `<element name="gold">
+  <immovable-solid />
+  <tags>
+    <tag>shiny</tag>
+  </tags>
+</element>
+<element name="silver">
+  <immovable-solid />
+  <tags>
+    <tag>shiny</tag>
+  </tags>
+</element>
+<element name="magpie">
+  <immovable-solid />
+  <reactions>
+    <reaction>
+      <touching tag="shiny" />
+      <convert-neighbour>wall</convert-neighbour>
+    </reaction>
+  </reactions>
+</element>`
+
+	runReactionTests(t, []reactionTest{
+		{
+			name:     "touching a tag",
+			elements: shiny,
+			paint: []paint{
+				{Rect{4, 4, 4, 4}, "gold"},
+				{Rect{6, 6, 6, 6}, "silver"},
+				{Rect{5, 4, 5, 4}, "wood"},
+				{Rect{5, 5, 5, 5}, "magpie"},
+			},
+			ticks:    10,
+			expected: map[string]int{"gold": 0, "silver": 0, "wood": 1},
+		},
+		{
+			name: "fire spreads",
+			paint: []paint{
+				{Rect{3, 10, 8, 16}, "wood"},
+				{Rect{10, 10, 15, 16}, "sand"},
+				{Rect{5, 13, 5, 13}, "fire"},
+			},
+			ticks:    200,
+			expected: map[string]int{"wood": 0, "sand": 42},
+		},
+	})
+}
+
+func TestBurningRates(t *testing.T) {
+	// Embers set fire to what touches them, but never burn out themselves.
+	ember := `<element name="ember">
+  <tags>
+    <tag>burning</tag>
+  </tags>
+  <immovable-solid />
+  <material>
+    <density>10</density>
+  </material>
+</element>`
+
+	// Molten wax has not burnt yet either.
+	fuels := map[string][]string{
+		"wood": {"wood"},
+		"wax":  {"wax", "molten-wax"},
+	}
+	left := map[string]int{}
+	var tests []reactionTest
+	for _, fuel := range []string{"wood", "wax"} {
+		tests = append(tests, reactionTest{
+			name:     fuel,
+			elements: ember,
+			paint:    []paint{{Rect{2, 17, 17, 17}, "ember"}, {Rect{2, 16, 17, 16}, fuel}},
+			ticks:    8,
+			check: func(t *testing.T, world *World) {
+				for _, element := range fuels[fuel] {
+					left[fuel] += countCells(world, lookup(t, world, element))
+				}
+			},
+		})
+	}
+	runReactionTests(t, tests)
+
+	if left["wood"] >= left["wax"] {
+		t.Errorf("%v cells of wood and %v cells of wax left, expected wood to burn faster", left["wood"], left["wax"])
+	}
+}
@@ -0,0 +1,50 @@
+package game
+
+// Rect is an inclusive rectangle of cell positions. A rect whose minimum is
+// past its maximum is empty.
+type Rect struct {
+	MinX, MinY, MaxX, MaxY int
+}
+
+func EmptyRect() Rect {
+	return Rect{0, 0, -1, -1}
+}
+
+func (rect Rect) Empty() bool {
+	return rect.MinX > rect.MaxX || rect.MinY > rect.MaxY
+}
+
+func (rect Rect) Union(other Rect) Rect {
+	if rect.Empty() {
+		return other
+	}
+	if other.Empty() {
+		return rect
+	}
+	return Rect{
+		min(rect.MinX, other.MinX),
+		min(rect.MinY, other.MinY),
+		max(rect.MaxX, other.MaxX),
+		max(rect.MaxY, other.MaxY),
+	}
+}
+
+func (rect Rect) Intersect(other Rect) Rect {
+	return Rect{
+		max(rect.MinX, other.MinX),
+		max(rect.MinY, other.MinY),
+		min(rect.MaxX, other.MaxX),
+		min(rect.MaxY, other.MaxY),
+	}
+}
+
+func (rect Rect) Area() int {
+	if rect.Empty() {
+		return 0
+	}
+	return (rect.MaxX - rect.MinX + 1) * (rect.MaxY - rect.MinY + 1)
+}
+
+func (rect Rect) Contains(x, y int) bool {
+	return x >= rect.MinX && x <= rect.MaxX && y >= rect.MinY && y <= rect.MaxY
+}
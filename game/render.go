@@ -0,0 +1,30 @@
+package game
+
+// TakeModified reports whether any cell of the chunk changed since the last
+// call, and resets the flag.
+func (chunk *Chunk) TakeModified() bool {
+	return chunk.modified.Swap(false)
+}
+
+// MarkModified flags the chunk for a redraw without changing any cell.
+func (chunk *Chunk) MarkModified() {
+	chunk.modified.Store(true)
+}
+
+// FillPixels writes the colour of every cell of the chunk into pix as
+// premultiplied RGBA, one row of the chunk after the other. pix must hold at
+// least 4*ChunkWidth*ChunkHeight bytes.
+func (chunk *Chunk) FillPixels(pix []byte) {
+	world := chunk.World
+	for y := range world.ChunkHeight {
+		for x := range world.ChunkWidth {
+			i := world.CalculateCellIndex(x, y)
+			col := world.ElementData[chunk.Cells[i].Type].RGBA
+			p := pix[i*4 : i*4+4 : i*4+4]
+			p[0] = col.R
+			p[1] = col.G
+			p[2] = col.B
+			p[3] = col.A
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package game
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestFillPixels(t *testing.T) {
+	world := newTestWorld(t, 2, 1, 4, 3)
+	sand := lookup(t, world, "sand")
+	chunk := world.Chunks[1]
+	cell, _ := chunk.GetCell(1, 2)
+	cell.SetType(sand)
+
+	pix := make([]byte, 4*world.ChunkArea())
+	chunk.FillPixels(pix)
+
+	for i := range chunk.Cells {
+		expected := world.ElementData[chunk.Cells[i].Type].RGBA
+		got := color.RGBA{pix[i*4], pix[i*4+1], pix[i*4+2], pix[i*4+3]}
+		if got != expected {
+			t.Errorf("pixel %v is %v, expected %v", i, got, expected)
+		}
+	}
+	if i := world.CalculateCellIndex(1, 2); pix[i*4] != world.ElementData[sand].RGBA.R {
+		t.Error("the sand cell wasn't drawn as sand")
+	}
+}
+
+func TestTakeModified(t *testing.T) {
+	world := newTestWorld(t, 2, 1, 4, 4)
+	for _, chunk := range world.Chunks {
+		chunk.TakeModified()
+	}
+
+	cell, _ := world.GetCell(5, 1)
+	cell.SetType(lookup(t, world, "wood"))
+
+	for _, test := range []struct {
+		chunk    *Chunk
+		modified bool
+	}{
+		{world.Chunks[0], false},
+		{world.Chunks[1], true},
+		{world.Chunks[1], false},
+	} {
+		if modified := test.chunk.TakeModified(); modified != test.modified {
+			t.Errorf("chunk %v %v modified: %v, expected %v", test.chunk.X, test.chunk.Y, modified, test.modified)
+		}
+	}
+}
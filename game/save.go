@@ -0,0 +1,458 @@
+package game
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// SAVE_MAGIC starts every save file.
+const SAVE_MAGIC = "FSND"
+
+// SAVE_VERSION is the version of the save format written by Save. Load
+// accepts every version up to and including it.
+//
+//	1: element table, dirty rects and cell types
+//	2: cell temperatures
+//	3: infinite worlds and chunk positions
+//	4: boundaries
+//	5: element properties and the property values of cells
+//	6: the tick every cell was born at
+const SAVE_VERSION = 6
+
+// Save writes the world to out in the binary save format. Infinite worlds
+// save the chunks they streamed out too.
+//
+// Cells are stored as indices into an element name table rather than by
+// their numeric type, so saves keep working when elements are added to or
+// removed from the data folder.
+func (w *World) Save(out io.Writer) error {
+	writer := bufio.NewWriter(out)
+
+	header := []any{
+		[]byte(SAVE_MAGIC),
+		uint16(SAVE_VERSION),
+		w.Seed,
+		w.Tick,
+		uint32(w.Width), uint32(w.Height),
+		uint32(w.ChunkWidth), uint32(w.ChunkHeight),
+		uint16(len(w.ElementData)),
+	}
+	for _, value := range header {
+		if err := binary.Write(writer, binary.LittleEndian, value); err != nil {
+			return fmt.Errorf("error while writing save header: %v", err)
+		}
+	}
+
+	for id := range len(w.ElementData) {
+		data := w.ElementData[id]
+		if err := writeString(writer, data.ElementTypeName); err != nil {
+			return fmt.Errorf("error while writing element table: %v", err)
+		}
+		if err := binary.Write(writer, binary.LittleEndian, uint16(len(data.Properties))); err != nil {
+			return fmt.Errorf("error while writing element table: %v", err)
+		}
+		for _, property := range data.Properties {
+			if err := writeString(writer, property.Name); err != nil {
+				return fmt.Errorf("error while writing element table: %v", err)
+			}
+		}
+	}
+
+	var stored [][2]int
+	if w.Store != nil {
+		stored = w.Store.Positions()
+	}
+
+	infinite := uint8(0)
+	if w.Infinite {
+		infinite = 1
+	}
+	for _, value := range []any{infinite, uint32(len(w.Chunks) + len(stored))} {
+		if err := binary.Write(writer, binary.LittleEndian, value); err != nil {
+			return fmt.Errorf("error while writing save header: %v", err)
+		}
+	}
+
+	for _, boundary := range w.Boundaries {
+		values := []any{uint8(slices.Index(BOUNDARY_MODES, boundary.Mode)), uint16(boundary.Element), boundary.Rate}
+		for _, value := range values {
+			if err := binary.Write(writer, binary.LittleEndian, value); err != nil {
+				return fmt.Errorf("error while writing boundaries: %v", err)
+			}
+		}
+	}
+
+	// Element ids are the indices of the element table, so the cells of a
+	// chunk can be written as they are.
+	buffer := make([]byte, 0, 8+w.CellsSize(SAVE_VERSION, w.DataSlots))
+	for _, chunk := range w.Chunks {
+		buffer = buffer[:0]
+		buffer = binary.LittleEndian.AppendUint32(buffer, uint32(int32(chunk.X)))
+		buffer = binary.LittleEndian.AppendUint32(buffer, uint32(int32(chunk.Y)))
+		buffer = chunk.AppendCells(buffer)
+		if _, err := writer.Write(buffer); err != nil {
+			return fmt.Errorf("error while writing chunk %v %v: %v", chunk.X, chunk.Y, err)
+		}
+	}
+	for _, position := range stored {
+		data, err := w.Store.Read(position[0], position[1])
+		if err != nil {
+			return err
+		}
+		buffer = buffer[:0]
+		buffer = binary.LittleEndian.AppendUint32(buffer, uint32(int32(position[0])))
+		buffer = binary.LittleEndian.AppendUint32(buffer, uint32(int32(position[1])))
+		buffer = append(buffer, data...)
+		if _, err := writer.Write(buffer); err != nil {
+			return fmt.Errorf("error while writing chunk %v %v: %v", position[0], position[1], err)
+		}
+	}
+
+	return writer.Flush()
+}
+
+// AppendCells appends the dirty rect and the cells of the chunk to buffer,
+// with cells stored by their element id and properties by their slot.
+func (chunk *Chunk) AppendCells(buffer []byte) []byte {
+	dirty := chunk.NextDirty()
+	buffer = binary.LittleEndian.AppendUint32(buffer, uint32(int32(dirty.MinX)))
+	buffer = binary.LittleEndian.AppendUint32(buffer, uint32(int32(dirty.MinY)))
+	buffer = binary.LittleEndian.AppendUint32(buffer, uint32(int32(dirty.MaxX)))
+	buffer = binary.LittleEndian.AppendUint32(buffer, uint32(int32(dirty.MaxY)))
+	for i := range chunk.Cells {
+		buffer = binary.LittleEndian.AppendUint16(buffer, uint16(chunk.Cells[i].Type))
+	}
+	for i := range chunk.Cells {
+		buffer = binary.LittleEndian.AppendUint32(buffer, math.Float32bits(chunk.Cells[i].Temperature))
+	}
+	for _, value := range chunk.Data {
+		buffer = binary.LittleEndian.AppendUint32(buffer, uint32(value))
+	}
+	for i := range chunk.Cells {
+		buffer = binary.LittleEndian.AppendUint64(buffer, chunk.Cells[i].BornAt)
+	}
+	return buffer
+}
+
+// CellsSize returns how many bytes AppendCells writes for a chunk, in the
+// given version of the save format and with the given number of data slots
+// per cell.
+func (w *World) CellsSize(version uint16, dataSlots int) int {
+	cellSize := 2
+	if version >= 2 {
+		cellSize += 4
+	}
+	if version >= 5 {
+		cellSize += 4 * dataSlots
+	}
+	if version >= 6 {
+		cellSize += 8
+	}
+	return 16 + w.ChunkArea()*cellSize
+}
+
+// ElementMapping maps the element ids and property slots of a save onto
+// those of the world loading it.
+type ElementMapping struct {
+	// Elements maps the element ids of the save to the ids of the world.
+	Elements []int
+	// Slots maps the property slots of every element of the save to the
+	// slots of the world, or to -1 for properties the element doesn't have
+	// anymore.
+	Slots [][]int
+	// DataSlots is how many property values the save has per cell.
+	DataSlots int
+}
+
+// ReadCells reads back what AppendCells wrote. mapping translates the
+// element ids and property slots in buffer to those of the world, or is nil
+// if they are the same.
+func (chunk *Chunk) ReadCells(buffer []byte, mapping *ElementMapping, version uint16) error {
+	world := chunk.World
+	dataSlots := world.DataSlots
+	if mapping != nil {
+		dataSlots = mapping.DataSlots
+	}
+	if len(buffer) < world.CellsSize(version, dataSlots) {
+		return fmt.Errorf("chunk %v %v is truncated", chunk.X, chunk.Y)
+	}
+
+	chunk.dirtyLock.Lock()
+	chunk.nextDirty = Rect{
+		int(int32(binary.LittleEndian.Uint32(buffer[0:]))),
+		int(int32(binary.LittleEndian.Uint32(buffer[4:]))),
+		int(int32(binary.LittleEndian.Uint32(buffer[8:]))),
+		int(int32(binary.LittleEndian.Uint32(buffer[12:]))),
+	}
+	chunk.dirtyLock.Unlock()
+
+	temperatures := buffer[16+world.ChunkArea()*2:]
+	var values, births []byte
+	if version >= 5 {
+		values = temperatures[world.ChunkArea()*4:]
+		births = values[world.ChunkArea()*dataSlots*4:]
+	}
+	for i := range chunk.Cells {
+		cell := &chunk.Cells[i]
+		index := int(binary.LittleEndian.Uint16(buffer[16+i*2:]))
+		if mapping == nil {
+			if _, ok := world.ElementData[index]; !ok {
+				return fmt.Errorf("cell %v of chunk %v %v has invalid element %v", i, chunk.X, chunk.Y, index)
+			}
+			cell.Type = index
+		} else if index >= len(mapping.Elements) {
+			return fmt.Errorf("cell %v of chunk %v %v has invalid element index %v", i, chunk.X, chunk.Y, index)
+		} else {
+			cell.Type = mapping.Elements[index]
+		}
+
+		if version >= 2 {
+			cell.Temperature = math.Float32frombits(binary.LittleEndian.Uint32(temperatures[i*4:]))
+		} else {
+			cell.Temperature = cell.ElementData().Temperature
+		}
+
+		// Cells of older saves are as old as the save.
+		cell.BornAt = world.Tick
+		if version >= 6 {
+			cell.BornAt = binary.LittleEndian.Uint64(births[i*8:])
+		}
+
+		cell.ResetData()
+		if version < 5 {
+			continue
+		}
+		data := cell.Data()
+		for slot := range dataSlots {
+			value := int32(binary.LittleEndian.Uint32(values[(i*dataSlots+slot)*4:]))
+			if mapping == nil {
+				data[slot] = value
+			} else if slot < len(mapping.Slots[index]) && mapping.Slots[index][slot] >= 0 {
+				data[mapping.Slots[index][slot]] = value
+			}
+		}
+	}
+
+	chunk.MarkModified()
+	return nil
+}
+
+// LoadGame creates a world from a save written by Save. Saves only name their
+// elements, so the elements are loaded from dataFolder, as with NewWorld.
+func LoadGame(in io.Reader, dataFolder string) (*World, error) {
+	world, err := newWorld(0, 0, 1, 1, false, 0, dataFolder)
+	if err != nil {
+		return nil, err
+	}
+	if err := world.Load(in); err != nil {
+		return nil, err
+	}
+	return world, nil
+}
+
+// Load replaces the contents of the world with a save written by Save. The
+// world keeps its own element definitions; every element named in the save
+// has to exist in them. The save is read into a copy of the world first, so
+// the world is left as it was if anything in the save is wrong.
+func (w *World) Load(in io.Reader) error {
+	reader := bufio.NewReader(in)
+
+	magic := make([]byte, len(SAVE_MAGIC))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return fmt.Errorf("error while reading save header: %v", err)
+	}
+	if string(magic) != SAVE_MAGIC {
+		return errors.New("not a save file")
+	}
+
+	var version uint16
+	if err := binary.Read(reader, binary.LittleEndian, &version); err != nil {
+		return fmt.Errorf("error while reading save header: %v", err)
+	}
+	if version == 0 || version > SAVE_VERSION {
+		return fmt.Errorf("unsupported save version %v", version)
+	}
+
+	var seed int64
+	var tick uint64
+	var width, height, chunkWidth, chunkHeight uint32
+	var elementCount uint16
+	for _, value := range []any{&seed, &tick, &width, &height, &chunkWidth, &chunkHeight, &elementCount} {
+		if err := binary.Read(reader, binary.LittleEndian, value); err != nil {
+			return fmt.Errorf("error while reading save header: %v", err)
+		}
+	}
+
+	if chunkWidth == 0 || chunkHeight == 0 {
+		return fmt.Errorf("invalid chunk size %vx%v", chunkWidth, chunkHeight)
+	}
+
+	mapping := &ElementMapping{
+		Elements: make([]int, elementCount),
+		Slots:    make([][]int, elementCount),
+	}
+	elements := mapping.Elements
+	for i := range elements {
+		name, err := readString(reader)
+		if err != nil {
+			return fmt.Errorf("error while reading element table: %v", err)
+		}
+		if id, ok := w.LookupElement("", name); !ok {
+			return fmt.Errorf("save uses element '%v' which is not defined", name)
+		} else {
+			elements[i] = id
+		}
+		if version < 5 {
+			continue
+		}
+
+		var propertyCount uint16
+		if err := binary.Read(reader, binary.LittleEndian, &propertyCount); err != nil {
+			return fmt.Errorf("error while reading element table: %v", err)
+		}
+		mapping.Slots[i] = make([]int, propertyCount)
+		mapping.DataSlots = max(mapping.DataSlots, int(propertyCount))
+		for slot := range mapping.Slots[i] {
+			property, err := readString(reader)
+			if err != nil {
+				return fmt.Errorf("error while reading element table: %v", err)
+			}
+			// Properties the element lost are dropped, and new ones start
+			// out at their default.
+			if worldSlot, ok := w.ElementData[elements[i]].PropertySlot(property); ok {
+				mapping.Slots[i][slot] = worldSlot
+			} else {
+				mapping.Slots[i][slot] = -1
+			}
+		}
+	}
+
+	infinite := uint8(0)
+	chunkCount := width * height
+	if version >= 3 {
+		for _, value := range []any{&infinite, &chunkCount} {
+			if err := binary.Read(reader, binary.LittleEndian, value); err != nil {
+				return fmt.Errorf("error while reading save header: %v", err)
+			}
+		}
+	}
+
+	if infinite == 0 && (width == 0 || height == 0) {
+		return fmt.Errorf("invalid world size %vx%v chunks", width, height)
+	}
+
+	// Before version 4 every edge was a wall.
+	boundaries := WallBoundaries()
+	if version >= 4 {
+		for edge := range boundaries {
+			var mode uint8
+			var element uint16
+			var rate float32
+			for _, value := range []any{&mode, &element, &rate} {
+				if err := binary.Read(reader, binary.LittleEndian, value); err != nil {
+					return fmt.Errorf("error while reading boundaries: %v", err)
+				}
+			}
+			if int(mode) >= len(BOUNDARY_MODES) {
+				return fmt.Errorf("invalid boundary mode %v on the %v edge", mode, EDGE_NAMES[edge])
+			}
+			if int(element) >= len(elements) {
+				return fmt.Errorf("invalid element index %v on the %v edge", element, EDGE_NAMES[edge])
+			}
+			boundaries[edge] = Boundary{Mode: BOUNDARY_MODES[mode], Element: elements[element], Rate: rate}
+		}
+	}
+
+	loaded := *w
+	loaded.Chunks, loaded.Phases, loaded.Store = nil, nil, nil
+	loaded.Seed = seed
+	loaded.Rand = rand.New(rand.NewSource(seed))
+	loaded.Tick = tick
+	loaded.Width, loaded.Height = int(width), int(height)
+	loaded.ChunkWidth, loaded.ChunkHeight = int(chunkWidth), int(chunkHeight)
+	loaded.Infinite = infinite != 0
+	if err := loaded.setBoundaries(boundaries); err != nil {
+		return err
+	}
+	loaded.CreateChunks()
+
+	// Before version 3 every chunk of the world was saved, row by row.
+	positions := make([][2]int, 0, len(loaded.Chunks))
+	for _, chunk := range loaded.Chunks {
+		positions = append(positions, [2]int{chunk.X, chunk.Y})
+	}
+
+	buffer := make([]byte, 8+loaded.CellsSize(version, mapping.DataSlots))
+	for i := range int(chunkCount) {
+		data := buffer[:loaded.CellsSize(version, mapping.DataSlots)]
+		var x, y int
+		if version >= 3 {
+			if _, err := io.ReadFull(reader, buffer); err != nil {
+				return fmt.Errorf("error while reading chunk: %v", err)
+			}
+			x = int(int32(binary.LittleEndian.Uint32(buffer[0:])))
+			y = int(int32(binary.LittleEndian.Uint32(buffer[4:])))
+			data = buffer[8:]
+		} else {
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return fmt.Errorf("error while reading chunk: %v", err)
+			}
+			x, y = positions[i][0], positions[i][1]
+		}
+
+		chunk, err := loaded.GetChunk(x, y)
+		if err != nil && loaded.Infinite {
+			chunk, err = NewChunk(&loaded, x, y), nil
+			loaded.addChunk(chunk)
+		}
+		if err != nil {
+			return err
+		}
+		if err := chunk.ReadCells(data, mapping, version); err != nil {
+			return err
+		}
+	}
+	loaded.refreshChunks()
+
+	// Everything was read, so the world can take over the loaded chunks.
+	// Chunks streamed out before belong to the old world.
+	if w.Store != nil {
+		w.Store.Clear()
+	}
+	loaded.Store = w.Store
+	*w = loaded
+	for _, chunk := range w.Chunks {
+		chunk.World = w
+	}
+
+	return nil
+}
+
+func writeString(writer io.Writer, s string) error {
+	if len(s) > 0xFFFF {
+		return fmt.Errorf("string '%v' is too long", s)
+	}
+	if err := binary.Write(writer, binary.LittleEndian, uint16(len(s))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, s)
+	return err
+}
+
+func readString(reader io.Reader) (string, error) {
+	var length uint16
+	if err := binary.Read(reader, binary.LittleEndian, &length); err != nil {
+		return "", err
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
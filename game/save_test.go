@@ -0,0 +1,286 @@
+package game
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestLoadTruncatedLeavesWorldUnchanged(t *testing.T) {
+	world := newTestWorld(t, 4, 4, 10, 10)
+	sand, _ := world.LookupElement("", "sand")
+	for x := 5; x < 30; x++ {
+		cell, _ := world.GetCell(x, 5)
+		cell.Place(sand)
+	}
+	if err := world.Step(10); err != nil {
+		t.Fatal(err)
+	}
+	before := saveBytes(t, world)
+
+	other := newTestWorld(t, 3, 3, 16, 16)
+	save := saveBytes(t, other)
+
+	for _, size := range []int{len(save) - 1, len(save) / 2, 40} {
+		if err := world.Load(bytes.NewReader(save[:size])); err == nil {
+			t.Fatalf("loading %v of %v bytes succeeded", size, len(save))
+		}
+		if after := saveBytes(t, world); !bytes.Equal(before, after) {
+			t.Fatalf("loading %v of %v bytes changed the world", size, len(save))
+		}
+	}
+
+	if err := world.Step(10); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveLoadStep(t *testing.T) {
+	expected := newTestWorld(t, 4, 4, 10, 10)
+	paintScene(t, expected)
+	if err := expected.Step(200); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := newTestWorld(t, 4, 4, 10, 10)
+	paintScene(t, saved)
+	if err := saved.Step(100); err != nil {
+		t.Fatal(err)
+	}
+	save := saveBytes(t, saved)
+
+	// The world loading the save starts out with another size and seed.
+	loaded, err := NewWorld(2, 3, 5, 7, 1, "../data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+	if err := loaded.Load(bytes.NewReader(save)); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Step(100); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(saveBytes(t, loaded), saveBytes(t, expected)) {
+		t.Error("stepping a loaded save ended up with other cells than stepping without saving")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	world := newTestWorld(t, 2, 2, 10, 10)
+	paintScene(t, world)
+	save := saveBytes(t, world)
+
+	tests := []struct {
+		name   string
+		change func(save []byte) []byte
+	}{
+		{"not a save", func(save []byte) []byte { return []byte("not a save file") }},
+		{"newer version", func(save []byte) []byte {
+			binary.LittleEndian.PutUint16(save[len(SAVE_MAGIC):], SAVE_VERSION+1)
+			return save
+		}},
+		{"no chunks", func(save []byte) []byte {
+			binary.LittleEndian.PutUint32(save[len(SAVE_MAGIC)+2+8+8:], 0)
+			return save
+		}},
+		{"unknown element", func(save []byte) []byte { return bytes.Replace(save, []byte("sand"), []byte("sane"), 1) }},
+		{"truncated", func(save []byte) []byte { return save[:len(save)-1] }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loaded := newTestWorld(t, 1, 1, 10, 10)
+			if err := loaded.Load(bytes.NewReader(test.change(bytes.Clone(save)))); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadGame(t *testing.T) {
+	saved := newTestWorld(t, 3, 2, 8, 12)
+	paintScene(t, saved)
+	if err := saved.Step(50); err != nil {
+		t.Fatal(err)
+	}
+	save := saveBytes(t, saved)
+
+	loaded, err := LoadGame(bytes.NewReader(save), "../data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+	if !bytes.Equal(saveBytes(t, loaded), save) {
+		t.Fatal("the loaded world saves differently from the world it was saved from")
+	}
+
+	for _, world := range []*World{saved, loaded} {
+		if err := world.Step(50); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(saveBytes(t, loaded), saveBytes(t, saved)) {
+		t.Error("the loaded world went another way than the world it was saved from")
+	}
+
+	if _, err := LoadGame(bytes.NewReader(save[:len(save)/2]), "../data"); err == nil {
+		t.Error("loading half a save succeeded")
+	}
+}
+
+// saveVersion writes the world in an older version of the save format, by
+// leaving out whatever came after that version.
+func saveVersion(t *testing.T, world *World, version uint16) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	write := func(value any) {
+		if err := binary.Write(&buffer, binary.LittleEndian, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeName := func(s string) {
+		write(uint16(len(s)))
+		buffer.WriteString(s)
+	}
+
+	buffer.WriteString(SAVE_MAGIC)
+	write(version)
+	write(world.Seed)
+	write(world.Tick)
+	write([]uint32{uint32(world.Width), uint32(world.Height), uint32(world.ChunkWidth), uint32(world.ChunkHeight)})
+	write(uint16(len(world.ElementData)))
+	for id := range len(world.ElementData) {
+		data := world.ElementData[id]
+		writeName(data.ElementTypeName)
+		if version >= 5 {
+			write(uint16(len(data.Properties)))
+			for _, property := range data.Properties {
+				writeName(property.Name)
+			}
+		}
+	}
+	if version >= 3 {
+		write(uint8(0))
+		write(uint32(len(world.Chunks)))
+	}
+	if version >= 4 {
+		for _, boundary := range world.Boundaries {
+			write(uint8(slices.Index(BOUNDARY_MODES, boundary.Mode)))
+			write(uint16(boundary.Element))
+			write(boundary.Rate)
+		}
+	}
+
+	area := world.ChunkArea()
+	for _, chunk := range world.Chunks {
+		if version >= 3 {
+			write([]int32{int32(chunk.X), int32(chunk.Y)})
+		}
+		cells := chunk.AppendCells(nil)
+		types, cells := cells[:16+area*2], cells[16+area*2:]
+		temperatures, cells := cells[:area*4], cells[area*4:]
+		values, births := cells[:area*world.DataSlots*4], cells[area*world.DataSlots*4:]
+		buffer.Write(types)
+		if version >= 2 {
+			buffer.Write(temperatures)
+		}
+		if version >= 5 {
+			buffer.Write(values)
+		}
+		if version >= 6 {
+			buffer.Write(births)
+		}
+	}
+	return buffer.Bytes()
+}
+
+func TestLoadOlderVersions(t *testing.T) {
+	folder := testData(t, map[string]string{
+		"elements/marker.xml": `<element name="marker">
+  <immovable-solid />
+  <property name="mark" default="3" />
+  <reactions>
+    <reaction>
+      <set-property name="mark" value="7" />
+    </reaction>
+  </reactions>
+</element>`,
+	})
+
+	world, err := NewWorld(3, 3, 8, 8, 5, folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer world.Close()
+	boundaries := WallBoundaries()
+	boundaries[EDGE_BOTTOM] = Boundary{Mode: BOUNDARY_VOID}
+	if err := world.SetBoundaries(boundaries); err != nil {
+		t.Fatal(err)
+	}
+	paintScene(t, world)
+	marker := lookup(t, world, "marker")
+	for x := 3; x < 20; x++ {
+		cell, _ := world.GetCell(x, 20)
+		cell.Place(marker)
+	}
+	if err := world.Step(30); err != nil {
+		t.Fatal(err)
+	}
+	if cell, _ := world.GetCell(3, 20); cell.Data()[0] == 3 {
+		t.Fatal("marker never set its mark, so its property can't be told apart from the default")
+	}
+	if !bytes.Equal(saveVersion(t, world, SAVE_VERSION), saveBytes(t, world)) {
+		t.Fatal("saveVersion doesn't write the current version like Save does")
+	}
+
+	for version := uint16(1); version <= SAVE_VERSION; version++ {
+		t.Run(fmt.Sprintf("version %v", version), func(t *testing.T) {
+			loaded, err := NewWorld(1, 1, 4, 4, 1, folder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer loaded.Close()
+			if err := loaded.Load(bytes.NewReader(saveVersion(t, world, version))); err != nil {
+				t.Fatal(err)
+			}
+
+			if loaded.Tick != world.Tick || loaded.Width != world.Width || loaded.ChunkWidth != world.ChunkWidth {
+				t.Fatalf("loaded tick %v, %v chunks of %v cells, expected tick %v, %v chunks of %v cells",
+					loaded.Tick, loaded.Width, loaded.ChunkWidth, world.Tick, world.Width, world.ChunkWidth)
+			}
+			expectedBoundaries := WallBoundaries()
+			if version >= 4 {
+				expectedBoundaries = world.Boundaries
+			}
+			if loaded.Boundaries != expectedBoundaries {
+				t.Errorf("loaded boundaries %v, expected %v", loaded.Boundaries, expectedBoundaries)
+			}
+
+			for i, chunk := range world.Chunks {
+				for j := range chunk.Cells {
+					cell, other := &chunk.Cells[j], &loaded.Chunks[i].Cells[j]
+					expected := cell.Capture()
+					if version < 2 {
+						expected.Temperature = cell.ElementData().Temperature
+					}
+					if version < 5 {
+						for slot, property := range cell.ElementData().Properties {
+							expected.Data[slot] = property.Default
+						}
+					}
+					if version < 6 {
+						expected.BornAt = world.Tick
+					}
+					if got := other.Capture(); !reflect.DeepEqual(got, expected) {
+						t.Fatalf("cell %v %v loaded as %+v, expected %+v", expected.X, expected.Y, got, expected)
+					}
+				}
+			}
+		})
+	}
+}
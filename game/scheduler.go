@@ -0,0 +1,67 @@
+package game
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// PHASE_COUNT is the number of checkerboard phases a tick is split into.
+// Cells only ever reach into the chunks right next to their own, so chunks
+// two apart can be updated at the same time without touching the same cells.
+const PHASE_COUNT = 4
+
+// CanRunParallel reports whether chunks are large enough for the chunks of a
+// phase to stay out of each other's way. Wrapping an odd number of chunks
+// puts the first and last chunk of a row or column next to each other in
+// the same phase.
+func (w *World) CanRunParallel() bool {
+	if w.wrapX && w.Width > 1 && w.Width%2 == 1 || w.wrapY && w.Height > 1 && w.Height%2 == 1 {
+		return false
+	}
+	return w.Workers > 1 && w.ChunkWidth >= 2 && w.ChunkHeight >= 2
+}
+
+// UpdatePhase updates every chunk of a single phase, spreading them across
+// the configured number of workers.
+func (w *World) UpdatePhase(chunks []*Chunk) error {
+	if !w.CanRunParallel() || len(chunks) <= 1 {
+		for _, chunk := range chunks {
+			if err := chunk.Tick(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	workers := min(w.Workers, len(chunks))
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= len(chunks) {
+					return
+				}
+				if err := chunks[i].Tick(); err != nil {
+					errs[worker] = err
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package game
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParallelMatchesSerial(t *testing.T) {
+	wrap := func(t *testing.T, world *World) {
+		boundaries := WallBoundaries()
+		for edge := range boundaries {
+			boundaries[edge] = Boundary{Mode: BOUNDARY_WRAP}
+		}
+		if err := world.SetBoundaries(boundaries); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		create   func() (*World, error)
+		setup    func(t *testing.T, world *World)
+		parallel bool
+	}{
+		{
+			name:     "walls",
+			create:   func() (*World, error) { return NewWorld(4, 4, 10, 10, 3, "../data") },
+			parallel: true,
+		},
+		{
+			name:     "wrap",
+			create:   func() (*World, error) { return NewWorld(4, 4, 10, 10, 3, "../data") },
+			setup:    wrap,
+			parallel: true,
+		},
+		{
+			name:   "odd wrap",
+			create: func() (*World, error) { return NewWorld(3, 3, 12, 12, 3, "../data") },
+			setup:  wrap,
+		},
+		{
+			name:     "infinite",
+			create:   func() (*World, error) { return NewInfiniteWorld(8, 8, 3, "../data") },
+			parallel: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var expected []byte
+			for _, workers := range []int{1, 2, 4, 16} {
+				world, err := test.create()
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer world.Close()
+				world.Workers = workers
+				if test.setup != nil {
+					test.setup(t, world)
+				}
+				paintScene(t, world)
+
+				if workers > 1 && world.CanRunParallel() != test.parallel {
+					t.Fatalf("CanRunParallel() = %v, expected %v", !test.parallel, test.parallel)
+				}
+				if err := world.Step(150); err != nil {
+					t.Fatal(err)
+				}
+
+				save := saveBytes(t, world)
+				if expected == nil {
+					expected = save
+				} else if !bytes.Equal(save, expected) {
+					t.Errorf("%v workers ended up with other cells than 1 worker", workers)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package game
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go-falling-sand/util"
+)
+
+// STREAM_DISTANCE is how many chunks away from the focus of an infinite world
+// chunks stay loaded. Chunks further away are written to the store.
+const STREAM_DISTANCE = 4
+
+// ChunkStore keeps the chunks an infinite world streamed out in a folder,
+// one file per chunk. The files only mean something to the world that wrote
+// them, as cells are stored with the world's own element ids.
+type ChunkStore struct {
+	Folder string
+	// stored maps the position of every stored chunk to whether it was
+	// awake when it was streamed out.
+	stored map[[2]int]bool
+}
+
+// NewChunkStore creates a store in a fresh temporary folder.
+func NewChunkStore() (*ChunkStore, error) {
+	folder, err := os.MkdirTemp("", "falling-sand-chunks-")
+	if err != nil {
+		return nil, fmt.Errorf("error while creating chunk store: %v", err)
+	}
+	return &ChunkStore{Folder: folder, stored: map[[2]int]bool{}}, nil
+}
+
+func (store *ChunkStore) path(x, y int) string {
+	return filepath.Join(store.Folder, fmt.Sprintf("%v_%v.chunk", x, y))
+}
+
+// Has reports whether the chunk at the given chunk position is stored.
+func (store *ChunkStore) Has(x, y int) bool {
+	_, ok := store.stored[[2]int{x, y}]
+	return ok
+}
+
+// Positions returns the chunk positions of every stored chunk.
+func (store *ChunkStore) Positions() [][2]int {
+	positions := make([][2]int, 0, len(store.stored))
+	for position := range store.stored {
+		positions = append(positions, position)
+	}
+	return positions
+}
+
+// Awake returns the positions of the stored chunks that were awake when
+// they were streamed out.
+func (store *ChunkStore) Awake() [][2]int {
+	positions := [][2]int{}
+	for position, awake := range store.stored {
+		if awake {
+			positions = append(positions, position)
+		}
+	}
+	return positions
+}
+
+// Put writes a chunk to the store.
+func (store *ChunkStore) Put(chunk *Chunk) error {
+	if err := os.WriteFile(store.path(chunk.X, chunk.Y), chunk.AppendCells(nil), 0o644); err != nil {
+		return fmt.Errorf("error while storing chunk %v %v: %v", chunk.X, chunk.Y, err)
+	}
+	store.stored[[2]int{chunk.X, chunk.Y}] = !chunk.Sleeping()
+	return nil
+}
+
+// Read returns the cells of a stored chunk, as written by AppendCells.
+func (store *ChunkStore) Read(x, y int) ([]byte, error) {
+	data, err := os.ReadFile(store.path(x, y))
+	if err != nil {
+		return nil, fmt.Errorf("error while reading stored chunk %v %v: %v", x, y, err)
+	}
+	return data, nil
+}
+
+// Remove forgets a stored chunk.
+func (store *ChunkStore) Remove(x, y int) error {
+	delete(store.stored, [2]int{x, y})
+	if err := os.Remove(store.path(x, y)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Clear forgets every stored chunk.
+func (store *ChunkStore) Clear() error {
+	for position := range store.stored {
+		if err := store.Remove(position[0], position[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close deletes the folder of the store.
+func (store *ChunkStore) Close() error {
+	store.stored = map[[2]int]bool{}
+	return os.RemoveAll(store.Folder)
+}
+
+// Close releases the chunk store of an infinite world.
+func (w *World) Close() error {
+	if w.Store == nil {
+		return nil
+	}
+	err := w.Store.Close()
+	w.Store = nil
+	return err
+}
+
+// loadChunk creates the chunk at the given position, bringing it back from
+// the store if it was streamed out before. A fresh chunk holds nothing but
+// air and starts out asleep.
+func (w *World) loadChunk(x, y int) (*Chunk, error) {
+	chunk := NewChunk(w, x, y)
+	chunk.nextDirty = EmptyRect()
+	if w.Store != nil && w.Store.Has(x, y) {
+		data, err := w.Store.Read(x, y)
+		if err != nil {
+			return nil, err
+		}
+		if err := chunk.ReadCells(data, nil, SAVE_VERSION); err != nil {
+			return nil, err
+		}
+		if err := w.Store.Remove(x, y); err != nil {
+			return nil, err
+		}
+	}
+	w.addChunk(chunk)
+	w.wakeBorder(chunk)
+	return chunk, nil
+}
+
+// wakeBorder wakes the cells of the chunks around a chunk that was just
+// loaded which border it, as they may have been waiting for it to exist.
+// Air has nothing to wait for, so that chunks of air created next to each
+// other don't keep waking each other up and creating chunks without end.
+func (w *World) wakeBorder(chunk *Chunk) {
+	wake := func(x, y int) {
+		if cell, err := w.GetCell(x, y); err == nil && cell.Type != w.AirElement {
+			cell.KeepAwake()
+		}
+	}
+	bounds := chunk.WorldBounds()
+	for x := bounds.MinX - 1; x <= bounds.MaxX+1; x++ {
+		wake(x, bounds.MinY-1)
+		wake(x, bounds.MaxY+1)
+	}
+	for y := bounds.MinY; y <= bounds.MaxY; y++ {
+		wake(bounds.MinX-1, y)
+		wake(bounds.MaxX+1, y)
+	}
+}
+
+// unloadChunk writes a chunk to the store and removes it from the world.
+func (w *World) unloadChunk(chunk *Chunk) error {
+	if w.Store == nil {
+		store, err := NewChunkStore()
+		if err != nil {
+			return err
+		}
+		w.Store = store
+	}
+	if err := w.Store.Put(chunk); err != nil {
+		return err
+	}
+	w.removeChunk(chunk)
+	return nil
+}
+
+// FocusChunks returns the rect of chunk positions overlapping Focus, grown
+// by the given number of chunks on every side.
+func (w *World) FocusChunks(margin int) Rect {
+	if w.Focus.Empty() {
+		return EmptyRect()
+	}
+	return Rect{
+		util.FloorDiv(w.Focus.MinX, w.ChunkWidth) - margin,
+		util.FloorDiv(w.Focus.MinY, w.ChunkHeight) - margin,
+		util.FloorDiv(w.Focus.MaxX, w.ChunkWidth) + margin,
+		util.FloorDiv(w.Focus.MaxY, w.ChunkHeight) + margin,
+	}
+}
+
+// Stream brings the chunks of an infinite world in line with its focus. The
+// chunks under the focus are created, chunks too far from it are streamed
+// out and awake chunks that come back in range are streamed in again. Every
+// awake chunk gets its neighbours created so the cells in it have somewhere
+// to go. Without a focus nothing is streamed out, and awake chunks get their
+// neighbours wherever they are.
+func (w *World) Stream() error {
+	focused := !w.Focus.Empty()
+	visible := w.FocusChunks(0)
+	keep := w.FocusChunks(STREAM_DISTANCE)
+
+	for _, chunk := range w.Chunks {
+		if focused && !keep.Contains(chunk.X, chunk.Y) {
+			if err := w.unloadChunk(chunk); err != nil {
+				return err
+			}
+		}
+	}
+
+	for y := visible.MinY; y <= visible.MaxY; y++ {
+		for x := visible.MinX; x <= visible.MaxX; x++ {
+			if _, err := w.GetChunk(x, y); err == nil {
+				continue
+			}
+			if _, err := w.loadChunk(x, y); err != nil {
+				return err
+			}
+		}
+	}
+
+	if w.Store != nil {
+		for _, position := range w.Store.Awake() {
+			if !keep.Contains(position[0], position[1]) {
+				continue
+			}
+			if _, err := w.loadChunk(position[0], position[1]); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.refreshChunks()
+
+	awake := make([]*Chunk, 0, len(w.Chunks))
+	for _, chunk := range w.Chunks {
+		if !chunk.Sleeping() {
+			awake = append(awake, chunk)
+		}
+	}
+	for _, chunk := range awake {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				x, y := chunk.X+dx, chunk.Y+dy
+				if focused && !keep.Contains(x, y) {
+					continue
+				}
+				if _, err := w.GetChunk(x, y); err == nil {
+					continue
+				}
+				if _, err := w.loadChunk(x, y); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	w.refreshChunks()
+	return nil
+}
@@ -0,0 +1,236 @@
+package game
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// chunkStates captures every cell of a chunk.
+func chunkStates(chunk *Chunk) []CellState {
+	states := make([]CellState, len(chunk.Cells))
+	for i := range chunk.Cells {
+		states[i] = chunk.Cells[i].Capture()
+	}
+	return states
+}
+
+// findElement returns the first cell of the element, or nil if there is none.
+func findElement(world *World, element int) *Cell {
+	for _, chunk := range world.Chunks {
+		for i := range chunk.Cells {
+			if chunk.Cells[i].Type == element {
+				return &chunk.Cells[i]
+			}
+		}
+	}
+	return nil
+}
+
+func TestChunkStore(t *testing.T) {
+	world := newTestWorld(t, 2, 1, 8, 8)
+	paintScene(t, world)
+	if err := world.Step(5); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewChunkStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	for _, chunk := range world.Chunks {
+		if err := store.Put(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if positions := store.Positions(); len(positions) != 2 || !store.Has(0, 0) || !store.Has(1, 0) || store.Has(2, 0) {
+		t.Fatalf("the store holds %v, expected 0 0 and 1 0", positions)
+	}
+
+	for _, chunk := range world.Chunks {
+		data, err := store.Read(chunk.X, chunk.Y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		read := NewChunk(world, chunk.X, chunk.Y)
+		if err := read.ReadCells(data, nil, SAVE_VERSION); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(chunkStates(read), chunkStates(chunk)) {
+			t.Errorf("chunk %v %v came back with other cells", chunk.X, chunk.Y)
+		}
+		if read.NextDirty() != chunk.NextDirty() {
+			t.Errorf("chunk %v %v came back with %v awake, expected %v", chunk.X, chunk.Y, read.NextDirty(), chunk.NextDirty())
+		}
+	}
+
+	if err := store.Remove(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if store.Has(0, 0) {
+		t.Error("the store still has a removed chunk")
+	}
+	if _, err := store.Read(0, 0); err == nil {
+		t.Error("reading a removed chunk succeeded")
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(store.Folder); !os.IsNotExist(err) {
+		t.Errorf("the folder of a closed store is still there: %v", err)
+	}
+}
+
+func TestStreamOutAndIn(t *testing.T) {
+	// The focus starts on chunk 0 0 and moves to chunk 100 100, which
+	// streams chunk 0 0 out, and then to the given chunk.
+	tests := []struct {
+		name   string
+		awake  bool
+		focus  [2]int
+		loaded bool
+	}{
+		{"back in view", false, [2]int{0, 0}, true},
+		{"awake and in range", true, [2]int{STREAM_DISTANCE, 0}, true},
+		{"asleep and in range", false, [2]int{STREAM_DISTANCE, 0}, false},
+		{"awake and out of range", true, [2]int{STREAM_DISTANCE + 1, 0}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			world, err := NewInfiniteWorld(8, 8, 1, "../data")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer world.Close()
+			focus := func(x, y int) {
+				t.Helper()
+				world.Focus = Rect{x * 8, y * 8, x*8 + 7, y*8 + 7}
+				if err := world.Stream(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			focus(0, 0)
+			// Wood stays where it is, so its chunk falls asleep, while
+			// falling sand keeps its chunk awake.
+			element, ticks := "wood", 20
+			if test.awake {
+				element, ticks = "sand", 1
+			}
+			canvas := NewCanvas(world)
+			canvas.Element = lookup(t, world, element)
+			canvas.FillRect(Rect{1, 1, 6, 6})
+			if err := world.Step(ticks); err != nil {
+				t.Fatal(err)
+			}
+			if chunk, _ := world.GetChunk(0, 0); chunk.Sleeping() == test.awake {
+				t.Fatalf("the chunk is sleeping: %v, expected %v", chunk.Sleeping(), !test.awake)
+			}
+			chunk, _ := world.GetChunk(0, 0)
+			states := chunkStates(chunk)
+
+			focus(100, 100)
+			if _, err := world.GetChunk(0, 0); err == nil {
+				t.Fatal("the chunk far from the focus is still loaded")
+			}
+			if !world.Store.Has(0, 0) {
+				t.Fatal("the chunk far from the focus wasn't stored")
+			}
+
+			focus(test.focus[0], test.focus[1])
+			chunk, err = world.GetChunk(0, 0)
+			if loaded := err == nil; loaded != test.loaded {
+				t.Fatalf("the chunk is loaded: %v, expected %v", loaded, test.loaded)
+			}
+			if test.loaded && !reflect.DeepEqual(chunkStates(chunk), states) {
+				t.Error("the chunk came back with other cells")
+			}
+		})
+	}
+}
+
+func TestStreamWithoutFocus(t *testing.T) {
+	world, err := NewInfiniteWorld(8, 8, 1, "../data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer world.Close()
+	sand := lookup(t, world, "sand")
+	cell, _ := world.TouchCell(3, 2)
+	cell.Place(sand)
+
+	if err := world.Step(100); err != nil {
+		t.Fatal(err)
+	}
+	if cell := findElement(world, sand); cell == nil || cell.WorldY() < 50 {
+		t.Errorf("the sand stopped falling in a world without focus, %v chunks were created", len(world.Chunks))
+	}
+}
+
+func TestLoadedChunkWakesBorder(t *testing.T) {
+	world, err := NewInfiniteWorld(8, 8, 1, "../data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer world.Close()
+	sand := lookup(t, world, "sand")
+
+	// Chunk 0 1 is out of range of the focus, so the sand stops at the
+	// bottom of chunk 0 0 and its chunk falls asleep.
+	world.Focus = Rect{0, -8 * STREAM_DISTANCE, 7, -8*STREAM_DISTANCE + 7}
+	cell, _ := world.TouchCell(3, 2)
+	cell.Place(sand)
+	if err := world.Step(20); err != nil {
+		t.Fatal(err)
+	}
+	chunk, _ := world.GetChunk(0, 0)
+	if cell := findElement(world, sand); cell == nil || cell.WorldY() != 7 {
+		t.Fatal("the sand didn't stop at the bottom of its chunk")
+	}
+	if !chunk.Sleeping() {
+		t.Fatal("the chunk of the sand didn't fall asleep")
+	}
+
+	world.Focus = Rect{0, 8, 7, 15}
+	if err := world.Step(20); err != nil {
+		t.Fatal(err)
+	}
+	if cell := findElement(world, sand); cell == nil || cell.WorldY() <= 7 {
+		t.Error("the sand didn't fall into the chunk created below it")
+	}
+}
+
+func TestSaveStreamedWorld(t *testing.T) {
+	world, err := NewInfiniteWorld(8, 8, 1, "../data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer world.Close()
+	world.Focus = Rect{0, 0, 7, 7}
+	paintScene(t, world)
+	if err := world.Step(10); err != nil {
+		t.Fatal(err)
+	}
+	world.Focus = Rect{800, 800, 807, 807}
+	if err := world.Stream(); err != nil {
+		t.Fatal(err)
+	}
+	if len(world.Store.Positions()) == 0 {
+		t.Fatal("no chunk was streamed out")
+	}
+	save := saveBytes(t, world)
+
+	loaded, err := LoadGame(bytes.NewReader(save), "../data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+	if len(loaded.Chunks) != len(world.Chunks)+len(world.Store.Positions()) {
+		t.Errorf("the loaded world has %v chunks, expected the %v loaded and %v stored ones", len(loaded.Chunks), len(world.Chunks), len(world.Store.Positions()))
+	}
+}
@@ -0,0 +1,67 @@
+package game
+
+import (
+	"fmt"
+
+	"go-falling-sand/xml_handler"
+)
+
+// ElementSet is a set of element ids, stored as a bitset so conditions can
+// match a cell against many elements at the cost of one lookup.
+type ElementSet []uint64
+
+func (set *ElementSet) Add(id int) {
+	for len(*set) <= id/64 {
+		*set = append(*set, 0)
+	}
+	(*set)[id/64] |= 1 << (id % 64)
+}
+
+func (set ElementSet) Has(id int) bool {
+	return id/64 < len(set) && set[id/64]&(1<<(id%64)) != 0
+}
+
+// Tagged returns the set of elements carrying the tag. It is empty if no
+// element does.
+func (w *World) Tagged(tag string) ElementSet {
+	var set ElementSet
+	for id, data := range w.ElementData {
+		for _, other := range data.Tags {
+			if other == tag {
+				set.Add(id)
+				break
+			}
+		}
+	}
+	return set
+}
+
+// ParseElementSet reads which elements a reaction step matches: every
+// element with the tag in its tag attribute if it has one, and otherwise the
+// element named by its value, or by the attribute attr if that isn't empty.
+// Names are looked up from the pack of element.
+func (w *World) ParseElementSet(element int, step xmlhandler.ReactionStep, attr string) (ElementSet, error) {
+	name := step.Value
+	if attr != "" {
+		name = AttrValue(step.Attrs, attr)
+	}
+
+	if tag := AttrValue(step.Attrs, "tag"); tag != "" {
+		if name != "" {
+			return nil, fmt.Errorf("<%v> matches either an element or a tag, not both", step.XMLName.Local)
+		}
+		set := w.Tagged(tag)
+		if len(set) == 0 {
+			return nil, fmt.Errorf("no element has the tag '%v'", tag)
+		}
+		return set, nil
+	}
+
+	id, ok := w.lookupFrom(element, name)
+	if !ok {
+		return nil, fmt.Errorf("there is no element named '%v'", name)
+	}
+	var set ElementSet
+	set.Add(id)
+	return set, nil
+}
@@ -0,0 +1,194 @@
+package game
+
+import (
+	"fmt"
+	"slices"
+
+	"go-falling-sand/xml_handler"
+)
+
+// ResolveTemplates merges every definition that extends another with its
+// parent, parents first, so that the definitions returned stand on their
+// own. Definitions extending a missing parent or taking part in a cycle are
+// reported and left out. Templates are left out too, as they only exist to
+// be extended.
+func ResolveTemplates(definitions []*xmlhandler.XMLElementDefinition) ([]*xmlhandler.XMLElementDefinition, Diagnostics) {
+	var diagnostics Diagnostics
+
+	byName := map[string]*xmlhandler.XMLElementDefinition{}
+	for _, definition := range definitions {
+		if _, ok := byName[definition.Name]; !ok {
+			byName[definition.Name] = definition
+		}
+	}
+
+	resolved := map[*xmlhandler.XMLElementDefinition]*xmlhandler.XMLElementDefinition{}
+	failed := map[*xmlhandler.XMLElementDefinition]bool{}
+	resolving := map[*xmlhandler.XMLElementDefinition]bool{}
+
+	var resolve func(definition *xmlhandler.XMLElementDefinition) *xmlhandler.XMLElementDefinition
+	resolve = func(definition *xmlhandler.XMLElementDefinition) *xmlhandler.XMLElementDefinition {
+		if result, ok := resolved[definition]; ok {
+			return result
+		}
+		if failed[definition] {
+			return nil
+		}
+		if definition.Extends == "" {
+			resolved[definition] = definition
+			return definition
+		}
+
+		fail := func(format string, args ...any) *xmlhandler.XMLElementDefinition {
+			failed[definition] = true
+			diagnostics = append(diagnostics, Diagnostic{
+				File:    definition.File,
+				Line:    definition.Line,
+				Message: fmt.Sprintf(format, args...),
+			})
+			return nil
+		}
+
+		parent, ok := byName[definition.Extends]
+		if !ok {
+			return fail("'%v' extends '%v', which is not defined", definition.Name, definition.Extends)
+		}
+		if resolving[definition] {
+			return fail("'%v' extends itself through '%v'", definition.Name, definition.Extends)
+		}
+
+		resolving[definition] = true
+		resolvedParent := resolve(parent)
+		delete(resolving, definition)
+
+		if failed[definition] {
+			return nil
+		}
+		if resolvedParent == nil {
+			return fail("'%v' extends '%v', which could not be resolved", definition.Name, definition.Extends)
+		}
+
+		result := Extend(resolvedParent, definition)
+		resolved[definition] = result
+		return result
+	}
+
+	results := make([]*xmlhandler.XMLElementDefinition, 0, len(definitions))
+	for _, definition := range definitions {
+		if result := resolve(definition); result != nil && !definition.Template {
+			results = append(results, result)
+		}
+	}
+	return results, diagnostics
+}
+
+// Extend returns the definition of child with everything it leaves out
+// taken from parent. Reactions and tags add to those of the parent, and
+// properties of the same name override the parent's default. The name,
+// role and display name are never inherited.
+func Extend(parent, child *xmlhandler.XMLElementDefinition) *xmlhandler.XMLElementDefinition {
+	result := *child
+	result.Extends = ""
+
+	if child.Display == nil {
+		if parent.Display != nil {
+			display := *parent.Display
+			display.Name = ""
+			display.Unknown = nil
+			result.Display = &display
+		}
+	} else if parent.Display != nil {
+		display := *child.Display
+		if display.Color == "" {
+			display.Color = parent.Display.Color
+		}
+		if display.Selectable == nil {
+			display.Selectable = parent.Display.Selectable
+		}
+		result.Display = &display
+	}
+
+	if child.Material == nil {
+		if parent.Material != nil {
+			material := *parent.Material
+			material.Unknown = nil
+			result.Material = &material
+		}
+	} else if parent.Material != nil {
+		material := *child.Material
+		if material.Density == nil {
+			material.Density = parent.Material.Density
+		}
+		if material.Conductivity == nil {
+			material.Conductivity = parent.Material.Conductivity
+		}
+		if material.HeatCapacity == nil {
+			material.HeatCapacity = parent.Material.HeatCapacity
+		}
+		if material.Temperature == nil {
+			material.Temperature = parent.Material.Temperature
+		}
+		result.Material = &material
+	}
+
+	if child.Air == nil && child.ImmovableSolid == nil && child.MovableSolid == nil &&
+		child.Liquid == nil && child.Gas == nil && child.Dust == nil {
+		result.Air = parent.Air
+		result.ImmovableSolid = parent.ImmovableSolid
+		result.MovableSolid = parent.MovableSolid
+		result.Liquid = parent.Liquid
+		result.Gas = parent.Gas
+		result.Dust = parent.Dust
+	}
+
+	if parent.Reactions != nil {
+		reactions := &xmlhandler.XMLReactions{}
+		reactions.Reactions = slices.Clone(parent.Reactions.Reactions)
+		for i := range reactions.Reactions {
+			if reactions.Reactions[i].File == "" {
+				reactions.Reactions[i].File = parent.File
+			}
+		}
+		if child.Reactions != nil {
+			reactions.Reactions = append(reactions.Reactions, child.Reactions.Reactions...)
+			reactions.Unknown = child.Reactions.Unknown
+		}
+		result.Reactions = reactions
+	}
+
+	for _, transitions := range []struct {
+		result *[]xmlhandler.XMLTransition
+		parent []xmlhandler.XMLTransition
+	}{
+		{&result.MeltsAt, parent.MeltsAt},
+		{&result.BoilsAt, parent.BoilsAt},
+		{&result.IgnitesAt, parent.IgnitesAt},
+		{&result.FreezesAt, parent.FreezesAt},
+		{&result.CondensesAt, parent.CondensesAt},
+	} {
+		if len(*transitions.result) == 0 {
+			*transitions.result = transitions.parent
+		}
+	}
+
+	result.Properties = slices.Clone(parent.Properties)
+	for _, property := range child.Properties {
+		index := slices.IndexFunc(result.Properties, func(other xmlhandler.XMLProperty) bool {
+			return other.Name == property.Name
+		})
+		if index >= 0 {
+			result.Properties[index] = property
+		} else {
+			result.Properties = append(result.Properties, property)
+		}
+	}
+
+	result.Tags = slices.Clone(parent.Tags)
+	for _, tag := range child.Tags {
+		if !slices.Contains(result.Tags, tag) {
+			result.Tags = append(result.Tags, tag)
+		}
+	}
+
+	return &result
+}
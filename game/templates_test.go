@@ -0,0 +1,143 @@
+package game
+
+import (
+	"slices"
+	"testing"
+
+	"go-falling-sand/xml_handler"
+)
+
+func TestResolveTemplates(t *testing.T) {
+	type definition struct {
+		name, extends string
+		template      bool
+	}
+	tests := []struct {
+		name        string
+		definitions []definition
+		resolved    []string
+		diagnostics []string
+	}{
+		{
+			name:        "chain",
+			definitions: []definition{{"c", "b", false}, {"b", "a", false}, {"a", "", false}},
+			resolved:    []string{"c", "b", "a"},
+		},
+		{
+			name:        "templates are left out",
+			definitions: []definition{{"base", "", true}, {"a", "base", false}},
+			resolved:    []string{"a"},
+		},
+		{
+			name:        "missing parent",
+			definitions: []definition{{"a", "ghost", false}, {"b", "", false}},
+			resolved:    []string{"b"},
+			diagnostics: []string{"'a' extends 'ghost', which is not defined"},
+		},
+		{
+			name:        "itself",
+			definitions: []definition{{"a", "a", false}},
+			diagnostics: []string{"'a' extends itself through 'a'"},
+		},
+		{
+			name:        "cycle",
+			definitions: []definition{{"a", "b", false}, {"b", "c", false}, {"c", "a", false}, {"d", "a", false}},
+			diagnostics: []string{
+				"'a' extends itself through 'b'",
+				"'c' extends 'a', which could not be resolved",
+				"'b' extends 'c', which could not be resolved",
+				"'d' extends 'a', which could not be resolved",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			definitions := make([]*xmlhandler.XMLElementDefinition, len(test.definitions))
+			for i, definition := range test.definitions {
+				definitions[i] = &xmlhandler.XMLElementDefinition{
+					Name:     definition.name,
+					Extends:  definition.extends,
+					Template: definition.template,
+				}
+			}
+
+			resolved, diagnostics := ResolveTemplates(definitions)
+
+			var names []string
+			for _, definition := range resolved {
+				names = append(names, definition.Name)
+				if definition.Extends != "" {
+					t.Errorf("'%v' still extends '%v'", definition.Name, definition.Extends)
+				}
+			}
+			if !slices.Equal(names, test.resolved) {
+				t.Errorf("resolved %v, expected %v", names, test.resolved)
+			}
+
+			var messages []string
+			for _, diagnostic := range diagnostics {
+				messages = append(messages, diagnostic.Message)
+			}
+			if !slices.Equal(messages, test.diagnostics) {
+				t.Errorf("got diagnostics %q, expected %q", messages, test.diagnostics)
+			}
+		})
+	}
+}
+
+func TestExtend(t *testing.T) {
+	density := float32(5)
+	parent := &xmlhandler.XMLElementDefinition{
+		Name:         "parent",
+		Role:         ROLE_WALL,
+		File:         "parent.xml",
+		Display:      &xmlhandler.XMLDisplay{Name: "Parent", Color: "red"},
+		Material:     &xmlhandler.XMLMaterialData{Density: &density},
+		MovableSolid: &xmlhandler.XMLMovableSolidData{},
+		Reactions: &xmlhandler.XMLReactions{Reactions: []xmlhandler.XMLReaction{
+			{Line: 3},
+		}},
+		Properties: []xmlhandler.XMLProperty{{Name: "fuel", Default: 10}, {Name: "heat", Default: 1}},
+		Tags:       []string{"hot", "bright"},
+	}
+	child := &xmlhandler.XMLElementDefinition{
+		Name:       "child",
+		Extends:    "parent",
+		File:       "child.xml",
+		Display:    &xmlhandler.XMLDisplay{Color: "blue"},
+		Liquid:     &xmlhandler.XMLLiquidData{},
+		Reactions:  &xmlhandler.XMLReactions{Reactions: []xmlhandler.XMLReaction{{Line: 7}}},
+		Properties: []xmlhandler.XMLProperty{{Name: "fuel", Default: 20}},
+		Tags:       []string{"bright", "wet"},
+	}
+
+	result := Extend(parent, child)
+
+	if result.Name != "child" || result.Role != "" || result.Extends != "" {
+		t.Errorf("got name '%v', role '%v' and extends '%v'", result.Name, result.Role, result.Extends)
+	}
+	if result.Display.Name != "" || result.Display.Color != "blue" {
+		t.Errorf("got display name '%v' and color '%v'", result.Display.Name, result.Display.Color)
+	}
+	if result.Material == nil || result.Material.Density != &density {
+		t.Error("density wasn't inherited")
+	}
+	if result.Liquid == nil || result.MovableSolid != nil {
+		t.Error("the kind of the child didn't replace the one of the parent")
+	}
+	reactions := result.Reactions.Reactions
+	if len(reactions) != 2 || reactions[0].File != "parent.xml" || reactions[1].File != "" {
+		t.Errorf("got reactions %+v, expected the parent's from parent.xml and then the child's", reactions)
+	}
+	properties := []xmlhandler.XMLProperty{{Name: "fuel", Default: 20}, {Name: "heat", Default: 1}}
+	if !slices.Equal(result.Properties, properties) {
+		t.Errorf("got properties %v, expected %v", result.Properties, properties)
+	}
+	if tags := []string{"hot", "bright", "wet"}; !slices.Equal(result.Tags, tags) {
+		t.Errorf("got tags %v, expected %v", result.Tags, tags)
+	}
+	if parent.Reactions.Reactions[0].File != "" {
+		t.Error("extending changed the parent")
+	}
+}
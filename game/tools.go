@@ -0,0 +1,292 @@
+package game
+
+const (
+	BRUSH_CIRCLE = "circle"
+	BRUSH_SQUARE = "square"
+)
+
+const MAX_BRUSH_RADIUS = 32
+
+// Brush is the stamp painted at every point of a stroke.
+type Brush struct {
+	Shape  string
+	Radius int
+}
+
+// Stamp calls visit for every cell the brush covers when centred on x y.
+func (brush Brush) Stamp(x, y int, visit func(x, y int)) {
+	r := brush.Radius
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			if brush.Shape == BRUSH_CIRCLE && dx*dx+dy*dy > r*r+r {
+				continue
+			}
+			visit(x+dx, y+dy)
+		}
+	}
+}
+
+// TraceLine calls visit for every cell on the line from x0 y0 to x1 y1,
+// both ends included, so that fast mouse movements leave no gaps.
+func TraceLine(x0, y0, x1, y1 int, visit func(x, y int)) {
+	dx := x1 - x0
+	if dx < 0 {
+		dx = -dx
+	}
+	dy := y1 - y0
+	if dy > 0 {
+		dy = -dy
+	}
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+
+	err := dx + dy
+	for {
+		visit(x0, y0)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		if e2 := 2 * err; e2 >= dy {
+			err += dy
+			x0 += sx
+		} else {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// Canvas is what tools paint on: a world, the element being painted and the
+// brush to paint it with. Everything painted between BeginEdit and EndEdit
+// is recorded in History as one edit.
+type Canvas struct {
+	World   *World
+	Element int
+	Brush   Brush
+	History *History
+}
+
+func NewCanvas(world *World) *Canvas {
+	return &Canvas{
+		World:   world,
+		Element: world.AirElement,
+		Brush:   Brush{Shape: BRUSH_CIRCLE, Radius: 2},
+		History: NewHistory(),
+	}
+}
+
+func (canvas *Canvas) BeginEdit() {
+	canvas.History.Begin()
+}
+
+func (canvas *Canvas) EndEdit() {
+	canvas.History.End(canvas.World)
+}
+
+func (canvas *Canvas) Undo() bool {
+	return canvas.History.Undo(canvas.World)
+}
+
+func (canvas *Canvas) Redo() bool {
+	return canvas.History.Redo(canvas.World)
+}
+
+// Plot places the canvas element in a single cell. Positions outside of the
+// world are ignored.
+func (canvas *Canvas) Plot(x, y int) {
+	cell, err := canvas.World.TouchCell(x, y)
+	if err != nil {
+		return
+	}
+	canvas.History.Record(cell)
+	cell.Place(canvas.Element)
+}
+
+// Stamp paints the brush centred on x y.
+func (canvas *Canvas) Stamp(x, y int) {
+	canvas.Brush.Stamp(x, y, canvas.Plot)
+}
+
+// Stroke paints the brush along the line from x0 y0 to x1 y1.
+func (canvas *Canvas) Stroke(x0, y0, x1, y1 int) {
+	TraceLine(x0, y0, x1, y1, canvas.Stamp)
+}
+
+// FillRect paints every cell of rect.
+func (canvas *Canvas) FillRect(rect Rect) {
+	for y := rect.MinY; y <= rect.MaxY; y++ {
+		for x := rect.MinX; x <= rect.MaxX; x++ {
+			canvas.Plot(x, y)
+		}
+	}
+}
+
+// Fill paints the area of same-typed cells connected to x y. In infinite
+// worlds it stops at the edge of the loaded chunks.
+func (canvas *Canvas) Fill(x, y int) {
+	start, err := canvas.World.GetCell(x, y)
+	if err != nil || start.Type == canvas.Element {
+		return
+	}
+
+	target := start.Type
+	stack := [][2]int{{x, y}}
+	for len(stack) > 0 {
+		point := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		cell, err := canvas.World.GetCell(point[0], point[1])
+		if err != nil || cell.Type != target {
+			continue
+		}
+		canvas.Plot(point[0], point[1])
+
+		stack = append(stack,
+			[2]int{point[0] + 1, point[1]},
+			[2]int{point[0] - 1, point[1]},
+			[2]int{point[0], point[1] + 1},
+			[2]int{point[0], point[1] - 1},
+		)
+	}
+}
+
+// Tool turns a press, drag and release of the mouse into edits of a canvas.
+// Positions are in world cells and may lie outside of the world while
+// dragging.
+type Tool interface {
+	Name() string
+	Begin(canvas *Canvas, x, y int)
+	Move(canvas *Canvas, x, y int)
+	End(canvas *Canvas, x, y int)
+	// Preview calls visit for every cell the tool would paint if it ended
+	// now, for tools that only paint once they end.
+	Preview(canvas *Canvas, visit func(x, y int))
+}
+
+// BrushTool paints continuously while dragging.
+type BrushTool struct {
+	lastX, lastY int
+}
+
+func (tool *BrushTool) Name() string {
+	return "brush"
+}
+
+func (tool *BrushTool) Begin(canvas *Canvas, x, y int) {
+	tool.lastX, tool.lastY = x, y
+	canvas.Stamp(x, y)
+}
+
+func (tool *BrushTool) Move(canvas *Canvas, x, y int) {
+	canvas.Stroke(tool.lastX, tool.lastY, x, y)
+	tool.lastX, tool.lastY = x, y
+}
+
+func (tool *BrushTool) End(canvas *Canvas, x, y int) {}
+
+func (tool *BrushTool) Preview(canvas *Canvas, visit func(x, y int)) {}
+
+// LineTool paints a straight line from where the drag started to where it
+// ended.
+type LineTool struct {
+	active                     bool
+	startX, startY, endX, endY int
+}
+
+func (tool *LineTool) Name() string {
+	return "line"
+}
+
+func (tool *LineTool) Begin(canvas *Canvas, x, y int) {
+	tool.active = true
+	tool.startX, tool.startY = x, y
+	tool.endX, tool.endY = x, y
+}
+
+func (tool *LineTool) Move(canvas *Canvas, x, y int) {
+	tool.endX, tool.endY = x, y
+}
+
+func (tool *LineTool) End(canvas *Canvas, x, y int) {
+	tool.active = false
+	canvas.Stroke(tool.startX, tool.startY, x, y)
+}
+
+func (tool *LineTool) Preview(canvas *Canvas, visit func(x, y int)) {
+	if !tool.active {
+		return
+	}
+	TraceLine(tool.startX, tool.startY, tool.endX, tool.endY, func(x, y int) {
+		canvas.Brush.Stamp(x, y, visit)
+	})
+}
+
+// RectTool fills the rectangle spanned by the drag.
+type RectTool struct {
+	active                     bool
+	startX, startY, endX, endY int
+}
+
+func (tool *RectTool) Name() string {
+	return "rectangle"
+}
+
+func (tool *RectTool) Begin(canvas *Canvas, x, y int) {
+	tool.active = true
+	tool.startX, tool.startY = x, y
+	tool.endX, tool.endY = x, y
+}
+
+func (tool *RectTool) Move(canvas *Canvas, x, y int) {
+	tool.endX, tool.endY = x, y
+}
+
+func (tool *RectTool) End(canvas *Canvas, x, y int) {
+	tool.active = false
+	tool.endX, tool.endY = x, y
+	canvas.FillRect(tool.Rect())
+}
+
+func (tool *RectTool) Preview(canvas *Canvas, visit func(x, y int)) {
+	if !tool.active {
+		return
+	}
+	rect := tool.Rect()
+	for y := rect.MinY; y <= rect.MaxY; y++ {
+		for x := rect.MinX; x <= rect.MaxX; x++ {
+			visit(x, y)
+		}
+	}
+}
+
+// Rect returns the rectangle spanned by the current drag.
+func (tool *RectTool) Rect() Rect {
+	return Rect{
+		min(tool.startX, tool.endX),
+		min(tool.startY, tool.endY),
+		max(tool.startX, tool.endX),
+		max(tool.startY, tool.endY),
+	}
+}
+
+// FillTool flood fills the area under the cursor.
+type FillTool struct{}
+
+func (tool *FillTool) Name() string {
+	return "fill"
+}
+
+func (tool *FillTool) Begin(canvas *Canvas, x, y int) {
+	canvas.Fill(x, y)
+}
+
+func (tool *FillTool) Move(canvas *Canvas, x, y int) {}
+
+func (tool *FillTool) End(canvas *Canvas, x, y int) {}
+
+func (tool *FillTool) Preview(canvas *Canvas, visit func(x, y int)) {}
@@ -0,0 +1,125 @@
+package game
+
+import "testing"
+
+func TestTraceLine(t *testing.T) {
+	tests := []struct {
+		name           string
+		x0, y0, x1, y1 int
+	}{
+		{"point", 3, 3, 3, 3},
+		{"horizontal", 0, 0, 5, 0},
+		{"vertical up", 2, 0, 2, -4},
+		{"diagonal", 0, 0, 3, 3},
+		{"shallow", 0, 0, 7, 2},
+		{"steep backwards", 4, 9, 1, -2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var points [][2]int
+			TraceLine(test.x0, test.y0, test.x1, test.y1, func(x, y int) {
+				points = append(points, [2]int{x, y})
+			})
+
+			dx, dy := test.x1-test.x0, test.y1-test.y0
+			if expected := max(dx, -dx) + max(dy, -dy) + 1; len(points) != expected {
+				t.Fatalf("visited %v cells, expected %v", len(points), expected)
+			}
+			if first := points[0]; first != [2]int{test.x0, test.y0} {
+				t.Errorf("started at %v", first)
+			}
+			if last := points[len(points)-1]; last != [2]int{test.x1, test.y1} {
+				t.Errorf("ended at %v", last)
+			}
+			// Every cell touches the one before it on a side, so nothing can
+			// slip through the line.
+			for i := 1; i < len(points); i++ {
+				step := max(points[i][0]-points[i-1][0], points[i-1][0]-points[i][0]) +
+					max(points[i][1]-points[i-1][1], points[i-1][1]-points[i][1])
+				if step != 1 {
+					t.Fatalf("gap between %v and %v", points[i-1], points[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBrushStamp(t *testing.T) {
+	tests := []struct {
+		brush    Brush
+		expected int
+	}{
+		{Brush{BRUSH_SQUARE, 0}, 1},
+		{Brush{BRUSH_CIRCLE, 0}, 1},
+		{Brush{BRUSH_SQUARE, 2}, 25},
+		// The corners are cut off the circle.
+		{Brush{BRUSH_CIRCLE, 2}, 21},
+	}
+	for _, test := range tests {
+		visited := map[[2]int]bool{}
+		test.brush.Stamp(10, 20, func(x, y int) {
+			if max(x-10, 10-x) > test.brush.Radius || max(y-20, 20-y) > test.brush.Radius {
+				t.Errorf("%v brush of radius %v reached %v %v", test.brush.Shape, test.brush.Radius, x, y)
+			}
+			visited[[2]int{x, y}] = true
+		})
+		if len(visited) != test.expected || !visited[[2]int{10, 20}] {
+			t.Errorf("%v brush of radius %v covered %v cells, expected %v around its centre", test.brush.Shape, test.brush.Radius, len(visited), test.expected)
+		}
+	}
+}
+
+func TestFill(t *testing.T) {
+	tests := []struct {
+		name     string
+		infinite bool
+		wall     bool
+		x, y     int
+		expected int
+	}{
+		{name: "inside the walls", x: 5, y: 5, expected: 18 * 9},
+		{name: "behind the divider", x: 5, y: 15, expected: 18 * 8},
+		{name: "the walls", x: 0, y: 0, expected: 4*19 + 18},
+		{name: "outside of the world", x: -5, y: 3},
+		{name: "the same element", wall: true, x: 5, y: 10},
+		{name: "loaded chunks only", infinite: true, x: 3, y: 3, expected: 2 * 100},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var world *World
+			var err error
+			if test.infinite {
+				world, err = NewInfiniteWorld(10, 10, 1, "../data")
+			} else {
+				world, err = NewWorld(2, 2, 10, 10, 1, "../data")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer world.Close()
+
+			canvas := NewCanvas(world)
+			if test.infinite {
+				// Two chunks next to each other, and one further away.
+				for _, x := range []int{0, 10, 40} {
+					world.TouchCell(x, 0)
+				}
+			} else {
+				// A divider of walls across the middle of the world.
+				canvas.Element = world.WallElement
+				canvas.FillRect(Rect{1, 10, 18, 10})
+			}
+
+			canvas.Element = lookup(t, world, "sand")
+			if test.wall {
+				canvas.Element = world.WallElement
+			}
+			before := countCells(world, canvas.Element)
+			canvas.Fill(test.x, test.y)
+
+			if filled := countCells(world, canvas.Element) - before; filled != test.expected {
+				t.Errorf("filled %v cells, expected %v", filled, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,917 @@
+package game
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-falling-sand/util"
+	"go-falling-sand/xml_handler"
+)
+
+const ROLE_WALL = "wall"
+const ROLE_AIR = "air"
+const ROLE_NONE = "none"
+
+type ElementData struct {
+	Color           color.Color
+	RGBA            color.RGBA
+	Name            string
+	ElementTypeName string
+	ElementTypeID   int
+	Role            string
+	Selectable      bool
+	Kind            ElementKind
+	OtherKinds      []ElementKind
+	Bouyancy        float32
+
+	// Conductivity is how readily the element passes heat on to its
+	// neighbours, between 0 and 1, and HeatCapacity how much heat it takes to
+	// warm it up by one degree.
+	Conductivity float32
+	HeatCapacity float32
+
+	// Temperature is what fresh cells of the element start out at.
+	Temperature float32
+
+	// Transitions turn the element into another one once its temperature
+	// crosses a threshold.
+	Transitions []PhaseTransition
+
+	// Properties are the values every cell of the element keeps, see
+	// Cell.Data.
+	Properties []Property
+
+	// Tags group elements, so reactions can match all elements with a tag
+	// at once.
+	Tags []string
+
+	// Pack is the name of the pack the element comes from, or empty if it
+	// isn't part of one.
+	Pack string
+}
+
+type World struct {
+	elementIdCounter        int
+	AirElement              int
+	WallElement             int
+	ElementTypes            map[string]int
+	ElementData             map[int]*ElementData
+	Width, Height           int
+	ChunkWidth, ChunkHeight int
+	Chunks                  []*Chunk
+	Phases                  [][]*Chunk
+	PhaseOrder              []int
+	Workers                 int
+	Tick                    uint64
+	Seed                    int64
+	Rand                    *rand.Rand
+
+	// DataSlots is how many property values every cell has room for, which
+	// is the most properties any element has.
+	DataSlots int
+
+	// Infinite worlds have no size or walls. Their chunks are created when
+	// something touches them and streamed out to Store once they are far
+	// from Focus.
+	Infinite bool
+	Focus    Rect
+	Store    *ChunkStore
+
+	// Boundaries says what happens at each edge of a world of fixed size,
+	// indexed by the EDGE constants. Change them through SetBoundaries.
+	Boundaries [EDGE_COUNT]Boundary
+
+	// Packs are the element packs that were loaded, in the order they were
+	// loaded in.
+	Packs []*Pack
+
+	chunkMap         map[[2]int]*Chunk
+	chunksChanged    bool
+	updating         bool
+	wrapX, wrapY     bool
+	activeBoundaries bool
+}
+
+func (w *World) TotalWidth() int {
+	return w.Width * w.ChunkWidth
+}
+
+func (w *World) TotalHeight() int {
+	return w.Height * w.ChunkHeight
+}
+
+// Bounds returns the rect of world cells covered by chunks. For infinite
+// worlds that is only the chunks that are currently loaded.
+func (w *World) Bounds() Rect {
+	if !w.Infinite {
+		return Rect{0, 0, w.TotalWidth() - 1, w.TotalHeight() - 1}
+	}
+	bounds := EmptyRect()
+	for _, chunk := range w.Chunks {
+		bounds = bounds.Union(chunk.WorldBounds())
+	}
+	return bounds
+}
+
+func (w *World) DefineElement(
+	definition *xmlhandler.XMLElementDefinition,
+	elementTypeName string,
+	colorString string,
+	name string,
+	role string,
+	selectable bool,
+	bouyancy float32,
+) error {
+	diagnostics := CheckTags(definition)
+
+	displayLine := definition.Line
+	if definition.Display != nil {
+		displayLine = definition.Display.Line
+	}
+
+	var col, colErr = StringToColor(colorString)
+	if colErr != nil {
+		diagnostics.Add(displayLine, "invalid color '%v': %v", colorString, colErr)
+		col = color.White
+	}
+
+	if role == "" {
+		role = ROLE_NONE
+	} else if role != ROLE_AIR && role != ROLE_WALL && role != ROLE_NONE {
+		diagnostics.Add(definition.Line, "unknown role '%v', expected '%v', '%v' or '%v'", role, ROLE_AIR, ROLE_WALL, ROLE_NONE)
+		role = ROLE_NONE
+	}
+
+	var kind ElementKind = nil
+
+	if definition.MovableSolid != nil {
+		kind = &MovableSolid{}
+	} else if definition.Liquid != nil {
+		kind = &Liquid{}
+	} else if definition.Gas != nil {
+		kind = &Gas{definition.Gas.Weight}
+	} else if definition.Dust != nil {
+		kind = &Dust{definition.Dust.Weight}
+	} else if definition.ImmovableSolid != nil {
+		kind = &ImmovableSolid{}
+	} else {
+		kind = &DefaultKind{}
+	}
+
+	conductivity := float32(DEFAULT_CONDUCTIVITY)
+	heatCapacity := float32(DEFAULT_HEAT_CAPACITY)
+	temperature := float32(AMBIENT_TEMPERATURE)
+
+	if material := definition.Material; material != nil {
+		if material.Conductivity != nil {
+			conductivity = min(max(*material.Conductivity, 0), 1)
+		}
+		if material.HeatCapacity != nil {
+			if *material.HeatCapacity <= 0 {
+				diagnostics.Add(material.Line, "heat capacity of '%v' has to be positive", elementTypeName)
+			}
+			heatCapacity = *material.HeatCapacity
+		}
+		if material.Temperature != nil {
+			temperature = *material.Temperature
+		}
+	}
+
+	properties := make([]Property, 0, len(definition.Properties))
+	propertyLines := map[string]int{}
+	for _, property := range definition.Properties {
+		if property.Name == "" {
+			diagnostics.Add(property.Line, "property has no name")
+			continue
+		}
+		if line, ok := propertyLines[property.Name]; ok {
+			diagnostics.Add(property.Line, "property '%v' is already defined on line %v", property.Name, line)
+			continue
+		}
+		propertyLines[property.Name] = property.Line
+		properties = append(properties, Property{Name: property.Name, Default: property.Default})
+	}
+
+	tags := make([]string, 0, len(definition.Tags))
+	for _, tag := range definition.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			diagnostics.Add(definition.Line, "element '%v' has an empty tag", elementTypeName)
+		} else if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+
+	// Elements with problems are defined all the same, so the reactions
+	// naming them can still be checked.
+	if elementTypeName == "" {
+		return diagnostics
+	}
+
+	index := w.elementIdCounter
+	w.elementIdCounter++
+
+	w.ElementTypes[elementTypeName] = index
+	w.ElementData[index] = &ElementData{
+		Color:           col,
+		RGBA:            color.RGBAModel.Convert(col).(color.RGBA),
+		Name:            name,
+		ElementTypeName: elementTypeName,
+		ElementTypeID:   index,
+		Role:            role,
+		Selectable:      selectable,
+		Bouyancy:        bouyancy,
+		Kind:            kind,
+		OtherKinds:      make([]ElementKind, 0, 2),
+		Conductivity:    conductivity,
+		HeatCapacity:    heatCapacity,
+		Temperature:     temperature,
+		Properties:      properties,
+		Tags:            tags,
+		Pack:            definition.Pack,
+	}
+	w.DataSlots = max(w.DataSlots, len(properties))
+
+	if role == ROLE_AIR {
+		w.AirElement = index
+	} else if role == ROLE_WALL {
+		w.WallElement = index
+	}
+
+	return diagnostics.Err()
+}
+
+type ReactionStatement interface {
+	GetAction() (Action, error)
+	GetCondition() (Condition, error)
+}
+
+type ReactionActionStatement struct {
+	Action
+}
+
+func (reactionActionStatement *ReactionActionStatement) GetAction() (Action, error) {
+	return reactionActionStatement.Action, nil
+}
+
+func (reactionActionStatement *ReactionActionStatement) GetCondition() (Condition, error) {
+	return nil, errors.New("can't call 'GetCondition' on a ReactionStatement struct")
+}
+
+type ConditionReactionStatement struct {
+	Condition
+}
+
+func (conditionReactionStatement *ConditionReactionStatement) GetCondition() (Condition, error) {
+	return conditionReactionStatement.Condition, nil
+}
+
+func (conditionReactionStatement *ConditionReactionStatement) GetAction() (Action, error) {
+	return nil, errors.New("can't call 'GetAction' on a ConditionStatement struct")
+}
+
+// HandleReactionStep compiles the steps of a reaction of the given element.
+func (w *World) HandleReactionStep(element int, reactionSteps []xmlhandler.ReactionStep) ([]ReactionStatement, error) {
+	statements := make([]ReactionStatement, 0, len(reactionSteps))
+	var diagnostics Diagnostics
+	for _, v := range reactionSteps {
+		switch v.XMLName.Local {
+		case "turn-into":
+			{
+				if id, ok := w.lookupFrom(element, v.Value); !ok {
+					diagnostics.Add(v.Line, "there is no element named '%v'", v.Value)
+				} else {
+					statements = append(statements, &ReactionActionStatement{&TurnInto{id}})
+				}
+			}
+		case "emit":
+			{
+				id, ok := w.lookupFrom(element, v.Value)
+				if !ok {
+					diagnostics.Add(v.Line, "there is no element named '%v'", v.Value)
+					continue
+				}
+				emit := &Emit{ID: id}
+				if dir := AttrValue(v.Attrs, "dir"); dir != "" {
+					offset, ok := EMIT_DIRECTIONS[dir]
+					if !ok {
+						diagnostics.Add(v.Line, "unknown direction '%v'", dir)
+						continue
+					}
+					emit.Directed = true
+					emit.DX, emit.DY = offset[0], offset[1]
+				}
+				statements = append(statements, &ReactionActionStatement{emit})
+			}
+		case "convert-neighbour":
+			{
+				if id, ok := w.lookupFrom(element, v.Value); !ok {
+					diagnostics.Add(v.Line, "there is no element named '%v'", v.Value)
+				} else {
+					statements = append(statements, &ReactionActionStatement{&ConvertNeighbour{id}})
+				}
+			}
+		case "consume-neighbour":
+			{
+				statements = append(statements, &ReactionActionStatement{&ConsumeNeighbour{}})
+			}
+		case "swap-with":
+			{
+				if elements, err := w.ParseElementSet(element, v, ""); err != nil {
+					diagnostics.Add(v.Line, "%v", err)
+				} else {
+					statements = append(statements, &ReactionActionStatement{&SwapWith{elements}})
+				}
+			}
+		case "chance":
+			{
+				if chance, err := strconv.ParseFloat(v.Value, 32); err != nil {
+					diagnostics.Add(v.Line, "error while parsing float in xml: %v", err)
+				} else {
+					statements = append(statements, &ConditionReactionStatement{&Chance{float32(chance)}})
+				}
+			}
+		case "touching":
+			{
+				if elements, err := w.ParseElementSet(element, v, ""); err != nil {
+					diagnostics.Add(v.Line, "%v", err)
+				} else {
+					statements = append(statements, &ConditionReactionStatement{&Touching{elements}})
+				}
+			}
+		case "directly-touching":
+			{
+				if elements, err := w.ParseElementSet(element, v, ""); err != nil {
+					diagnostics.Add(v.Line, "%v", err)
+				} else {
+					statements = append(statements, &ConditionReactionStatement{&DirectlyTouching{elements}})
+				}
+			}
+		case "above", "below", "left-of", "right-of":
+			{
+				if elements, err := w.ParseElementSet(element, v, ""); err != nil {
+					diagnostics.Add(v.Line, "%v", err)
+				} else {
+					offset := DIRECTIONS[v.XMLName.Local]
+					statements = append(statements, &ConditionReactionStatement{&Adjacent{offset[0], offset[1], elements}})
+				}
+			}
+		case "neighbours":
+			{
+				if neighbours, err := w.ParseNeighbours(element, v); err != nil {
+					diagnostics.Add(v.Line, "error in <neighbours>: %v", err)
+				} else {
+					statements = append(statements, &ConditionReactionStatement{neighbours})
+				}
+			}
+		case "temperature":
+			{
+				if comparisons, err := ParseComparisons(v.Attrs); err != nil {
+					diagnostics.Add(v.Line, "error in <temperature>: %v", err)
+				} else {
+					statements = append(statements, &ConditionReactionStatement{&Temperature{comparisons}})
+				}
+			}
+		case "property":
+			{
+				if slot, ok := w.propertySlot(element, v, &diagnostics); !ok {
+					continue
+				} else if comparisons, err := ParseComparisons(v.Attrs, "name"); err != nil {
+					diagnostics.Add(v.Line, "error in <property>: %v", err)
+				} else {
+					statements = append(statements, &ConditionReactionStatement{&PropertyCondition{element, slot, comparisons}})
+				}
+			}
+		case "set-property":
+			{
+				if slot, ok := w.propertySlot(element, v, &diagnostics); !ok {
+					continue
+				} else if value, err := strconv.ParseInt(AttrValue(v.Attrs, "value"), 10, 32); err != nil {
+					diagnostics.Add(v.Line, "error while parsing integer in xml: %v", err)
+				} else {
+					statements = append(statements, &ReactionActionStatement{&SetProperty{element, slot, int32(value)}})
+				}
+			}
+		case "add-property", "subtract-property":
+			{
+				slot, ok := w.propertySlot(element, v, &diagnostics)
+				if !ok {
+					continue
+				}
+				amount := int64(1)
+				if value := AttrValue(v.Attrs, "value"); value != "" {
+					var err error
+					if amount, err = strconv.ParseInt(value, 10, 32); err != nil {
+						diagnostics.Add(v.Line, "error while parsing integer in xml: %v", err)
+						continue
+					}
+				}
+				if v.XMLName.Local == "subtract-property" {
+					amount = -amount
+				}
+				amount = min(max(amount, math.MinInt32), math.MaxInt32)
+				statements = append(statements, &ReactionActionStatement{&AddProperty{element, slot, int32(amount)}})
+			}
+		case "age":
+			{
+				if comparisons, err := ParseComparisons(v.Attrs); err != nil {
+					diagnostics.Add(v.Line, "error in <age>: %v", err)
+				} else {
+					statements = append(statements, &ConditionReactionStatement{&Age{comparisons}})
+				}
+			}
+		case "heat":
+			{
+				if amount, err := strconv.ParseFloat(v.Value, 32); err != nil {
+					diagnostics.Add(v.Line, "error while parsing float in xml: %v", err)
+				} else {
+					statements = append(statements, &ReactionActionStatement{&Heat{float32(amount)}})
+				}
+			}
+		case "end":
+			{
+				statements = append(statements, &ReactionActionStatement{&End{}})
+			}
+		case "any":
+			{
+				conds := w.HandleNestedConditions(element, v, &diagnostics)
+				statements = append(statements, &ConditionReactionStatement{&Any{conds}})
+			}
+		case "none":
+			{
+				conds := w.HandleNestedConditions(element, v, &diagnostics)
+				statements = append(statements, &ConditionReactionStatement{&None{conds}})
+			}
+		case "all":
+			{
+				conds := w.HandleNestedConditions(element, v, &diagnostics)
+				statements = append(statements, &ConditionReactionStatement{&All{conds}})
+			}
+		case "not":
+			{
+				conds := w.HandleNestedConditions(element, v, &diagnostics)
+				statements = append(statements, &ConditionReactionStatement{&Not{conds}})
+			}
+		default:
+			{
+				diagnostics.Add(v.Line, "unknown reaction step <%v>", v.XMLName.Local)
+			}
+		}
+	}
+	return statements, diagnostics.Err()
+}
+
+// HandleNestedConditions compiles the children of a step like <any> or
+// <not>, which may only hold conditions.
+func (w *World) HandleNestedConditions(element int, step xmlhandler.ReactionStep, diagnostics *Diagnostics) []Condition {
+	nested, err := w.HandleReactionStep(element, step.Steps)
+	diagnostics.Merge(step.Line, err)
+	conds := make([]Condition, 0, len(nested))
+	for _, stmt := range nested {
+		if cond, err := stmt.GetCondition(); err == nil {
+			conds = append(conds, cond)
+		} else {
+			diagnostics.Add(step.Line, "<%v> can only contain conditions, but got action", step.XMLName.Local)
+		}
+	}
+	return conds
+}
+
+// ParseNeighbours reads the attributes of a <neighbours> step. "of" names
+// the element to count, or "tag" the tag of the elements to count. "min"
+// and "max" bound the count, and "neighbourhood" says which cells count as
+// neighbours, moore by default.
+func (w *World) ParseNeighbours(element int, step xmlhandler.ReactionStep) (*Neighbours, error) {
+	neighbours := &Neighbours{Min: -1, Max: -1, Neighbourhood: MOORE_NEIGHBOURHOOD}
+	for _, attr := range step.Attrs {
+		switch attr.Name.Local {
+		case "of", "tag":
+		case "neighbourhood":
+			neighbourhood, ok := NEIGHBOURHOODS[attr.Value]
+			if !ok {
+				return nil, fmt.Errorf("unknown neighbourhood '%v', expected 'moore' or 'von-neumann'", attr.Value)
+			}
+			neighbours.Neighbourhood = neighbourhood
+		case "min", "max":
+			count, err := strconv.Atoi(attr.Value)
+			if err != nil || count < 0 {
+				return nil, fmt.Errorf("%v has to be a whole number of cells, got '%v'", attr.Name.Local, attr.Value)
+			}
+			if attr.Name.Local == "min" {
+				neighbours.Min = count
+			} else {
+				neighbours.Max = count
+			}
+		default:
+			return nil, fmt.Errorf("unknown attribute '%v'", attr.Name.Local)
+		}
+	}
+
+	if AttrValue(step.Attrs, "of") == "" && AttrValue(step.Attrs, "tag") == "" {
+		return nil, errors.New("expected the element to count in 'of' or a tag in 'tag'")
+	}
+	elements, err := w.ParseElementSet(element, step, "of")
+	if err != nil {
+		return nil, err
+	}
+	neighbours.Elements = elements
+	if neighbours.Min < 0 && neighbours.Max < 0 {
+		return nil, errors.New("expected at least one of min or max")
+	}
+	neighbours.Min = max(neighbours.Min, 0)
+	if neighbours.Max < 0 {
+		neighbours.Max = len(neighbours.Neighbourhood)
+	}
+	if neighbours.Min > neighbours.Max {
+		return nil, fmt.Errorf("min %v is larger than max %v", neighbours.Min, neighbours.Max)
+	}
+	if neighbours.Min > len(neighbours.Neighbourhood) {
+		return nil, fmt.Errorf("min %v is more than the %v cells of the neighbourhood", neighbours.Min, len(neighbours.Neighbourhood))
+	}
+	return neighbours, nil
+}
+
+// propertySlot looks up the property named by the name attribute of a step
+// among the properties of element.
+func (w *World) propertySlot(element int, step xmlhandler.ReactionStep, diagnostics *Diagnostics) (int, bool) {
+	name := AttrValue(step.Attrs, "name")
+	data := w.ElementData[element]
+	slot, ok := data.PropertySlot(name)
+	if !ok {
+		diagnostics.Add(step.Line, "element '%v' has no property named '%v'", data.ElementTypeName, name)
+	}
+	return slot, ok
+}
+
+func (w *World) DefineTransformations(definiton *xmlhandler.XMLElementDefinition) error {
+	index := w.ElementTypes[definiton.Name]
+	var diagnostics Diagnostics
+	if definiton.Reactions != nil {
+		for _, unknown := range definiton.Reactions.Unknown {
+			diagnostics.Add(unknown.Line, "unknown tag <%v> in <reactions>", unknown.XMLName.Local)
+		}
+	reactions:
+		for _, reaction := range definiton.Reactions.Reactions {
+			kind := &Reaction{
+				Actions:    make([]Action, 0, 2),
+				Conditions: make([]Condition, 0, 2),
+				Target:     -1,
+			}
+			statements, err := w.HandleReactionStep(index, reaction.Steps)
+			if err != nil {
+				var reactionDiagnostics Diagnostics
+				reactionDiagnostics.Merge(reaction.Line, err)
+				diagnostics = append(diagnostics, reactionDiagnostics.InFile(reaction.File)...)
+				continue
+			}
+			for _, statement := range statements {
+				action, err := statement.GetAction()
+				if err != nil {
+					condition, err := statement.GetCondition()
+					if err != nil {
+						diagnostics = append(diagnostics, Diagnostic{
+							File:    reaction.File,
+							Line:    reaction.Line,
+							Message: fmt.Sprintf("reaction of '%v' has a step that is neither a condition nor an action", definiton.Name),
+						})
+						continue reactions
+					}
+					kind.Conditions = append(kind.Conditions, condition)
+				} else {
+					kind.Actions = append(kind.Actions, action)
+				}
+			}
+			if slices.ContainsFunc(kind.Actions, func(action Action) bool {
+				_, ok := action.(TargetAction)
+				return ok
+			}) {
+				// The last condition that can pick a neighbour picks it.
+				for i, condition := range kind.Conditions {
+					if _, ok := condition.(TargetCondition); ok {
+						kind.Target = i
+					}
+				}
+				if kind.Target < 0 {
+					diagnostics = append(diagnostics, Diagnostic{
+						File:    reaction.File,
+						Line:    reaction.Line,
+						Message: "reaction acts on a neighbour, but has no condition like <touching> to pick one",
+					})
+					continue
+				}
+			}
+			elementData := w.ElementData[index]
+			elementData.OtherKinds = append(elementData.OtherKinds, kind)
+		}
+	}
+	return diagnostics.Err()
+}
+
+func (w *World) ChunkArea() int {
+	return w.ChunkWidth * w.ChunkHeight
+}
+
+func (w *World) WorldArea() int {
+	return w.Width * w.Height
+}
+
+func (w *World) HandleCommand(command *xmlhandler.XMLElementDefinition) error {
+	display := command.Display
+	if display == nil {
+		display = &xmlhandler.XMLDisplay{}
+	}
+
+	col := display.Color
+	if col == "" {
+		col = "white"
+	}
+
+	name := display.Name
+	if name == "" {
+		name = LocalName(command.Name)
+	}
+
+	material := command.Material
+	if material == nil {
+		material = &xmlhandler.XMLMaterialData{}
+	}
+
+	selectable := display.Selectable != nil && *display.Selectable
+
+	density := float32(0)
+	if material.Density != nil {
+		density = *material.Density
+	}
+
+	if err := w.DefineElement(command, command.Name, col, name, command.Role, selectable, density); err != nil {
+		return err
+	}
+	return nil
+}
+
+// CheckTags reports a missing name and every tag of a definition that
+// nothing understood.
+func CheckTags(command *xmlhandler.XMLElementDefinition) Diagnostics {
+	var diagnostics Diagnostics
+
+	if command.Name == "" {
+		diagnostics.Add(command.Line, "element has no name")
+	}
+	for _, unknown := range command.Unknown {
+		diagnostics.Add(unknown.Line, "unknown tag <%v> in <element>", unknown.XMLName.Local)
+	}
+	if command.Display != nil {
+		for _, unknown := range command.Display.Unknown {
+			diagnostics.Add(unknown.Line, "unknown tag <%v> in <display>", unknown.XMLName.Local)
+		}
+	}
+	if command.Material != nil {
+		for _, unknown := range command.Material.Unknown {
+			diagnostics.Add(unknown.Line, "unknown tag <%v> in <material>", unknown.XMLName.Local)
+		}
+	}
+	return diagnostics
+}
+
+func (w *World) HandleCommandReaction(command *xmlhandler.XMLElementDefinition) error {
+	var diagnostics Diagnostics
+	diagnostics.Merge(command.Line, w.DefineTransformations(command))
+	diagnostics.Merge(command.Line, w.DefinePhaseTransitions(command))
+	return diagnostics.Err()
+}
+
+func NewWorld(width, height int, chunkWidth, chunkHeight int, seed int64, dataFolder string) (*World, error) {
+	return newWorld(width, height, chunkWidth, chunkHeight, false, seed, dataFolder)
+}
+
+// NewInfiniteWorld creates a world without edges. It starts out empty, and
+// chunks are created as they get touched.
+func NewInfiniteWorld(chunkWidth, chunkHeight int, seed int64, dataFolder string) (*World, error) {
+	return newWorld(0, 0, chunkWidth, chunkHeight, true, seed, dataFolder)
+}
+
+func newWorld(width, height int, chunkWidth, chunkHeight int, infinite bool, seed int64, dataFolder string) (*World, error) {
+	world := &World{}
+
+	world.elementIdCounter = 0
+
+	world.Seed = seed
+	world.Rand = rand.New(rand.NewSource(seed))
+
+	world.Workers = runtime.NumCPU()
+
+	world.Width = width
+	world.Height = height
+
+	world.ChunkWidth = chunkWidth
+	world.ChunkHeight = chunkHeight
+
+	world.Infinite = infinite
+	world.Focus = EmptyRect()
+	world.Boundaries = WallBoundaries()
+
+	world.ElementData = map[int]*ElementData{}
+	world.ElementTypes = map[string]int{}
+
+	if err := world.LoadElements(dataFolder); err != nil {
+		return nil, err
+	}
+
+	world.CreateChunks()
+
+	return world, nil
+}
+
+// CreateChunks replaces every chunk of the world with fresh ones matching
+// its dimensions. Infinite worlds are left empty, and forget the chunks they
+// streamed out.
+func (w *World) CreateChunks() {
+	w.chunkMap = map[[2]int]*Chunk{}
+	w.chunksChanged = true
+	if w.Store != nil {
+		w.Store.Clear()
+	}
+
+	if !w.Infinite {
+		for y := range w.Height {
+			for x := range w.Width {
+				w.addChunk(NewChunk(w, x, y))
+			}
+		}
+	}
+
+	w.PhaseOrder = make([]int, PHASE_COUNT)
+	w.refreshChunks()
+}
+
+func (w *World) addChunk(chunk *Chunk) {
+	w.chunkMap[[2]int{chunk.X, chunk.Y}] = chunk
+	w.chunksChanged = true
+}
+
+func (w *World) removeChunk(chunk *Chunk) {
+	delete(w.chunkMap, [2]int{chunk.X, chunk.Y})
+	w.chunksChanged = true
+}
+
+// refreshChunks rebuilds Chunks and Phases after chunks were added or
+// removed. Chunks are kept sorted row by row.
+func (w *World) refreshChunks() {
+	if !w.chunksChanged {
+		return
+	}
+	w.chunksChanged = false
+
+	w.Chunks = w.Chunks[:0]
+	for _, chunk := range w.chunkMap {
+		w.Chunks = append(w.Chunks, chunk)
+	}
+	slices.SortFunc(w.Chunks, func(a, b *Chunk) int {
+		if a.Y != b.Y {
+			return cmp.Compare(a.Y, b.Y)
+		}
+		return cmp.Compare(a.X, b.X)
+	})
+
+	w.Phases = make([][]*Chunk, PHASE_COUNT)
+	for _, chunk := range w.Chunks {
+		phase := chunk.Phase()
+		w.Phases[phase] = append(w.Phases[phase], chunk)
+	}
+}
+
+func (w *World) CalculateCellIndex(x, y int) int {
+	return x + y*w.ChunkWidth
+}
+
+func (w *World) UpdateChunks() error {
+	if w.Infinite {
+		if err := w.Stream(); err != nil {
+			return err
+		}
+	}
+
+	// The phase order only depends on the seed and the tick, so a world
+	// loaded from a save carries on exactly like the one that was saved.
+	w.Rand.Seed(util.Mix(uint64(w.Seed), w.Tick))
+	for i := range w.PhaseOrder {
+		w.PhaseOrder[i] = i
+	}
+	util.Shuffle(w.Rand, w.PhaseOrder)
+	w.updating = true
+	defer func() { w.updating = false }()
+	for _, phase := range w.PhaseOrder {
+		if err := w.UpdatePhase(w.Phases[phase]); err != nil {
+			return err
+		}
+	}
+	w.Tick++
+	return nil
+}
+
+// Step advances the simulation by the given number of ticks.
+func (w *World) Step(ticks int) error {
+	for range ticks {
+		if err := w.UpdateChunks(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *World) GetChunk(chunkX, chunkY int) (*Chunk, error) {
+	if chunk, ok := w.chunkMap[[2]int{chunkX, chunkY}]; ok {
+		return chunk, nil
+	}
+	return nil, fmt.Errorf("there is no chunk at chunk position %v %v", chunkX, chunkY)
+}
+
+// TouchChunk returns the chunk at the given chunk position, creating it or
+// bringing it back from the store first if the world is infinite. It must
+// not be called while the world is updating.
+func (w *World) TouchChunk(chunkX, chunkY int) (*Chunk, error) {
+	if chunk, err := w.GetChunk(chunkX, chunkY); err == nil || !w.Infinite {
+		return chunk, err
+	}
+	chunk, err := w.loadChunk(chunkX, chunkY)
+	if err != nil {
+		return nil, err
+	}
+	w.refreshChunks()
+	return chunk, nil
+}
+
+func (w *World) GetCell(worldX, worldY int) (*Cell, error) {
+	worldX, worldY = w.wrap(worldX, worldY)
+	chunkX := util.FloorDiv(worldX, w.ChunkWidth)
+	chunkY := util.FloorDiv(worldY, w.ChunkHeight)
+
+	if chunk, err := w.GetChunk(chunkX, chunkY); err != nil {
+		return nil, fmt.Errorf("there is no cell at world position %v %v", worldX, worldY)
+	} else {
+		return chunk.GetCell(worldX-chunkX*w.ChunkWidth, worldY-chunkY*w.ChunkHeight)
+	}
+}
+
+// TouchCell is GetCell for edits from outside the simulation, which bring
+// the chunk of the cell into existence in infinite worlds.
+func (w *World) TouchCell(worldX, worldY int) (*Cell, error) {
+	worldX, worldY = w.wrap(worldX, worldY)
+	chunkX := util.FloorDiv(worldX, w.ChunkWidth)
+	chunkY := util.FloorDiv(worldY, w.ChunkHeight)
+
+	if chunk, err := w.TouchChunk(chunkX, chunkY); err != nil {
+		return nil, fmt.Errorf("there is no cell at world position %v %v", worldX, worldY)
+	} else {
+		return chunk.GetCell(worldX-chunkX*w.ChunkWidth, worldY-chunkY*w.ChunkHeight)
+	}
+}
+
+// WakeArea schedules every cell inside the given world rect for an update on
+// the next tick. Parts of the rect across a wrapping edge wake the cells on
+// the other side.
+func (w *World) WakeArea(rect Rect) {
+	if w.wrapX && !rect.Empty() {
+		width := w.TotalWidth()
+		if rect.MinX < 0 {
+			w.WakeArea(Rect{max(rect.MinX+width, 0), rect.MinY, width - 1, rect.MaxY})
+		}
+		if rect.MaxX >= width {
+			w.WakeArea(Rect{0, rect.MinY, min(rect.MaxX-width, width-1), rect.MaxY})
+		}
+	}
+	if w.wrapY && !rect.Empty() {
+		height := w.TotalHeight()
+		if rect.MinY < 0 {
+			w.WakeArea(Rect{rect.MinX, max(rect.MinY+height, 0), rect.MaxX, height - 1})
+		}
+		if rect.MaxY >= height {
+			w.WakeArea(Rect{rect.MinX, 0, rect.MaxX, min(rect.MaxY-height, height-1)})
+		}
+	}
+	if !w.Infinite {
+		rect = rect.Intersect(w.Bounds())
+	}
+	if rect.Empty() {
+		return
+	}
+	for chunkY := util.FloorDiv(rect.MinY, w.ChunkHeight); chunkY <= util.FloorDiv(rect.MaxY, w.ChunkHeight); chunkY++ {
+		for chunkX := util.FloorDiv(rect.MinX, w.ChunkWidth); chunkX <= util.FloorDiv(rect.MaxX, w.ChunkWidth); chunkX++ {
+			chunk, err := w.GetChunk(chunkX, chunkY)
+			if err != nil {
+				continue
+			}
+			offsetX := chunkX * w.ChunkWidth
+			offsetY := chunkY * w.ChunkHeight
+			chunk.Wake(Rect{rect.MinX - offsetX, rect.MinY - offsetY, rect.MaxX - offsetX, rect.MaxY - offsetY})
+		}
+	}
+}
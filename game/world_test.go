@@ -0,0 +1,164 @@
+package game
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestWorld creates a world of the bundled elements with a fixed seed.
+func newTestWorld(t *testing.T, width, height, chunkWidth, chunkHeight int) *World {
+	t.Helper()
+	world, err := NewWorld(width, height, chunkWidth, chunkHeight, 42, "../data")
+	if err != nil {
+		t.Fatalf("failed to create world: %v", err)
+	}
+	t.Cleanup(func() { world.Close() })
+	return world
+}
+
+// testData returns a data folder holding the bundled elements and the given
+// files, keyed by their path inside of the folder.
+func testData(t *testing.T, files map[string]string) string {
+	t.Helper()
+	folder := t.TempDir()
+	if err := os.CopyFS(folder, os.DirFS("../data")); err != nil {
+		t.Fatalf("failed to copy the bundled elements: %v", err)
+	}
+	writeFiles(t, folder, files)
+	return folder
+}
+
+// writeFiles writes the given files, keyed by their path inside of folder.
+func writeFiles(t *testing.T, folder string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		path = filepath.Join(folder, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// lookup returns the id of an element, failing the test if there is none.
+func lookup(t *testing.T, world *World, name string) int {
+	t.Helper()
+	id, ok := world.LookupElement("", name)
+	if !ok {
+		t.Fatalf("there is no element named '%v'", name)
+	}
+	return id
+}
+
+// countCells returns how many cells of the world are of the given element.
+func countCells(world *World, element int) int {
+	count := 0
+	for _, chunk := range world.Chunks {
+		for i := range chunk.Cells {
+			if chunk.Cells[i].Type == element {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// paintScene fills the world with strips of elements that react with each
+// other, so that stepping it exercises most of the simulation.
+func paintScene(t *testing.T, world *World) {
+	t.Helper()
+	names := []string{"sand", "water", "fire", "wood", "oil", "steam", "dust", "plant"}
+	for i, name := range names {
+		id := lookup(t, world, name)
+		for x := 2 + i*4; x < 5+i*4; x++ {
+			for y := 2; y < 15; y++ {
+				if cell, err := world.TouchCell(x, y); err == nil {
+					cell.Place(id)
+				}
+			}
+		}
+	}
+}
+
+// saveBytes returns the save of the world, which holds everything about its
+// cells and is handy to compare worlds by.
+func saveBytes(t *testing.T, world *World) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	if err := world.Save(&buffer); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func TestNewWorld(t *testing.T) {
+	world := newTestWorld(t, 2, 3, 10, 10)
+
+	for y := range world.TotalHeight() {
+		for x := range world.TotalWidth() {
+			cell, err := world.GetCell(x, y)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := world.AirElement
+			if x == 0 || y == 0 || x == world.TotalWidth()-1 || y == world.TotalHeight()-1 {
+				expected = world.WallElement
+			}
+			if cell.Type != expected || cell.WorldX() != x || cell.WorldY() != y {
+				t.Fatalf("cell %v %v is %v at %v %v, expected %v", x, y, cell.Type, cell.WorldX(), cell.WorldY(), expected)
+			}
+		}
+	}
+
+	for _, position := range [][2]int{{-1, 0}, {0, -1}, {20, 0}, {0, 30}} {
+		if _, err := world.GetCell(position[0], position[1]); err == nil {
+			t.Errorf("got a cell at %v %v, outside of the world", position[0], position[1])
+		}
+	}
+}
+
+func TestSameSeedSameWorld(t *testing.T) {
+	tests := []struct {
+		name  string
+		seeds [2]int64
+		same  bool
+	}{
+		{"same seed", [2]int64{7, 7}, true},
+		{"other seed", [2]int64{7, 8}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var worlds [2]*World
+			for i, seed := range test.seeds {
+				world, err := NewWorld(4, 4, 10, 10, seed, "../data")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer world.Close()
+				world.Workers = 1
+				paintScene(t, world)
+				if err := world.Step(100); err != nil {
+					t.Fatal(err)
+				}
+				worlds[i] = world
+			}
+
+			same := true
+			for i, chunk := range worlds[0].Chunks {
+				other := worlds[1].Chunks[i]
+				for j := range chunk.Cells {
+					if chunk.Cells[j].Type != other.Cells[j].Type || chunk.Cells[j].Temperature != other.Cells[j].Temperature {
+						same = false
+					}
+				}
+			}
+			if same != test.same {
+				t.Errorf("worlds are the same: %v, expected %v", same, test.same)
+			}
+		})
+	}
+}
@@ -2,9 +2,9 @@ package util
 
 import "math/rand"
 
-func Shuffle[T any](array []T) {
+func Shuffle[T any](rng *rand.Rand, array []T) {
 	for i := len(array) - 1; i > 0; i-- {
-		j := rand.Intn(i + 1)
+		j := rng.Intn(i + 1)
 		array[i], array[j] = array[j], array[i]
 	}
 }
@@ -40,8 +40,8 @@ func GetDir(n int) (int, int) {
 	return dx, dy
 }
 
-func GetRandomDir() (int, int) {
-	return GetDir(rand.Intn(8))
+func GetRandomDir(rng *rand.Rand) (int, int) {
+	return GetDir(rng.Intn(8))
 }
 
 func GetAdjDir(n int) (int, int) {
@@ -63,6 +63,30 @@ func GetAdjDir(n int) (int, int) {
 	return dx, dy
 }
 
-func GetRandomAdjDir() (int, int) {
-	return GetAdjDir(rand.Intn(4))
+func GetRandomAdjDir(rng *rand.Rand) (int, int) {
+	return GetAdjDir(rng.Intn(4))
+}
+
+// Mix hashes the given values into a single well-distributed seed.
+func Mix(values ...uint64) int64 {
+	var h uint64 = 0x9e3779b97f4a7c15
+	for _, v := range values {
+		h ^= v + 0x9e3779b97f4a7c15 + (h << 6) + (h >> 2)
+		h ^= h >> 30
+		h *= 0xbf58476d1ce4e5b9
+		h ^= h >> 27
+		h *= 0x94d049bb133111eb
+		h ^= h >> 31
+	}
+	return int64(h)
+}
+
+// FloorDiv divides a by b rounding towards negative infinity, so that
+// negative positions land in the right chunk.
+func FloorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
 }
@@ -9,25 +9,92 @@ type XMLElementList struct {
 
 type XMLElementDefinition struct {
 	XMLName   xml.Name         `xml:"element"`
+	File      string           `xml:"-"`
+	Pack      string           `xml:"-"`
+	Line      int              `xml:"-"`
 	Name      string           `xml:"name,attr"`
 	Role      string           `xml:"role,attr"`
+	Extends   string           `xml:"extends,attr"`
+	Template  bool             `xml:"template,attr"`
 	Display   *XMLDisplay      `xml:"display"`
 	Material  *XMLMaterialData `xml:"material"`
 	Reactions *XMLReactions    `xml:"reactions"`
 
+	Properties []XMLProperty `xml:"property"`
+	Tags       []string      `xml:"tags>tag"`
+
 	Air            *XMLAirData
 	ImmovableSolid *XMLImmovableSolidData
 	MovableSolid   *XMLMovableSolidData
 	Liquid         *XMLLiquidData
 	Gas            *XMLGasData
 	Dust           *XMLDustData
+
+	MeltsAt     []XMLTransition `xml:"melts-at"`
+	BoilsAt     []XMLTransition `xml:"boils-at"`
+	IgnitesAt   []XMLTransition `xml:"ignites-at"`
+	FreezesAt   []XMLTransition `xml:"freezes-at"`
+	CondensesAt []XMLTransition `xml:"condenses-at"`
+
+	Unknown []XMLUnknown `xml:",any"`
+}
+
+func (definition *XMLElementDefinition) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	definition.Line, _ = d.InputPos()
+	type plain XMLElementDefinition
+	return d.DecodeElement((*plain)(definition), &start)
+}
+
+// XMLUnknown collects tags that nothing else claimed, so that validation can
+// point them out.
+type XMLUnknown struct {
+	XMLName xml.Name
+	Line    int `xml:"-"`
+}
+
+func (unknown *XMLUnknown) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	unknown.Line, _ = d.InputPos()
+	type plain XMLUnknown
+	return d.DecodeElement((*plain)(unknown), &start)
+}
+
+type XMLProperty struct {
+	Name    string `xml:"name,attr"`
+	Default int32  `xml:"default,attr"`
+	Line    int    `xml:"-"`
+}
+
+func (property *XMLProperty) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	property.Line, _ = d.InputPos()
+	type plain XMLProperty
+	return d.DecodeElement((*plain)(property), &start)
+}
+
+type XMLTransition struct {
+	Temp float32 `xml:"temp,attr"`
+	Into string  `xml:"into,attr"`
+	Line int     `xml:"-"`
+}
+
+func (transition *XMLTransition) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	transition.Line, _ = d.InputPos()
+	type plain XMLTransition
+	return d.DecodeElement((*plain)(transition), &start)
 }
 
 type XMLDisplay struct {
-	XMLName    xml.Name `xml:"display"`
-	Name       string   `xml:"name"`
-	Color      string   `xml:"color"`
-	Selectable bool     `xml:"selectable"`
+	XMLName    xml.Name     `xml:"display"`
+	Name       string       `xml:"name"`
+	Color      string       `xml:"color"`
+	Selectable *bool        `xml:"selectable"`
+	Line       int          `xml:"-"`
+	Unknown    []XMLUnknown `xml:",any"`
+}
+
+func (display *XMLDisplay) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	display.Line, _ = d.InputPos()
+	type plain XMLDisplay
+	return d.DecodeElement((*plain)(display), &start)
 }
 
 type XMLAirData struct {
@@ -57,22 +124,70 @@ type XMLDustData struct {
 }
 
 type XMLMaterialData struct {
-	XMLName xml.Name `xml:"material"`
-	Density float32  `xml:"density"`
+	XMLName      xml.Name     `xml:"material"`
+	Density      *float32     `xml:"density"`
+	Conductivity *float32     `xml:"conductivity"`
+	HeatCapacity *float32     `xml:"heat-capacity"`
+	Temperature  *float32     `xml:"temperature"`
+	Line         int          `xml:"-"`
+	Unknown      []XMLUnknown `xml:",any"`
+}
+
+func (material *XMLMaterialData) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	material.Line, _ = d.InputPos()
+	type plain XMLMaterialData
+	return d.DecodeElement((*plain)(material), &start)
 }
 
 type XMLReactions struct {
 	XMLName   xml.Name      `xml:"reactions"`
 	Reactions []XMLReaction `xml:"reaction"`
+	Unknown   []XMLUnknown  `xml:",any"`
 }
 
 type XMLReaction struct {
 	XMLName xml.Name       `xml:"reaction"`
 	Steps   []ReactionStep `xml:",any"`
+	Line    int            `xml:"-"`
+	// File is set on reactions inherited from a definition in another
+	// file, so problems with them point there.
+	File string `xml:"-"`
+}
+
+func (reaction *XMLReaction) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	reaction.Line, _ = d.InputPos()
+	type plain XMLReaction
+	return d.DecodeElement((*plain)(reaction), &start)
 }
 
 type ReactionStep struct {
 	XMLName xml.Name
+	Attrs   []xml.Attr     `xml:",any,attr"`
 	Value   string         `xml:",chardata"`
 	Steps   []ReactionStep `xml:",any"`
+	Line    int            `xml:"-"`
+}
+
+func (step *ReactionStep) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	step.Line, _ = d.InputPos()
+	type plain ReactionStep
+	return d.DecodeElement((*plain)(step), &start)
+}
+
+type XMLPack struct {
+	XMLName      xml.Name `xml:"pack"`
+	Name         string   `xml:"name,attr"`
+	Version      string   `xml:"version,attr"`
+	Dependencies []string `xml:"depends"`
+}
+
+type XMLPalette struct {
+	XMLName xml.Name          `xml:"palette"`
+	Entries []XMLPaletteEntry `xml:"entry"`
+}
+
+type XMLPaletteEntry struct {
+	XMLName xml.Name `xml:"entry"`
+	Color   string   `xml:"color,attr"`
+	Element string   `xml:"element,attr"`
 }